@@ -0,0 +1,273 @@
+package ckks
+
+import (
+	"bytes"
+	"encoding/pem"
+	"fmt"
+	"math/big"
+	"sort"
+	"strconv"
+	"strings"
+
+	"ckks/negacyclic"
+)
+
+// This file implements an armored text encoding of keys and ciphertexts, in
+// the style of PEM: the binary serialization (see serialization.go) is written
+// in base64 between BEGIN and END lines, preceded by typed headers which
+// describe the object, e.g.
+//
+//	-----BEGIN CKKS CIPHERTEXT-----
+//	Degree: 1
+//	Fingerprint: 4c1e...
+//	Level: 2
+//	Modulus-Bits: 210
+//	Version: 1
+//
+//	Q0tLUwECAAAEAA...
+//	-----END CKKS CIPHERTEXT-----
+//
+// The fingerprint identifies the instance of the object (see
+// Instance.Fingerprint). Decoding checks it, and checks that the headers
+// describe the decoded object.
+
+// Types of the armored blocks.
+const (
+	ArmorCiphertext    = "CKKS CIPHERTEXT"
+	ArmorKey           = "CKKS KEY"
+	ArmorPublicKey     = "CKKS PUBLIC KEY"
+	ArmorSecretKey     = "CKKS SECRET KEY"
+	ArmorEvaluationKey = "CKKS EVALUATION KEY"
+	ArmorRotationKeys  = "CKKS ROTATION KEYS"
+)
+
+// EncodeArmored returns the armored text encoding of obj, which is one of
+// *Ciphertext, *Key, *PublicKey, *SecretKey, *EvaluationKey and *RotationKeys,
+// generated by this instance.
+func (ins *Instance) EncodeArmored(obj interface{}) ([]byte, error) {
+	blockType, headers, err := ins.armorHeaders(obj)
+	if err != nil {
+		return nil, err
+	}
+	body, err := obj.(interface{ MarshalBinary() ([]byte, error) }).MarshalBinary()
+	if err != nil {
+		return nil, err
+	}
+	return pem.EncodeToMemory(&pem.Block{Type: blockType, Headers: headers, Bytes: body}), nil
+}
+
+// DecodeArmored decodes the armored text encoding of an object into obj, which
+// must have the type of the encoded object (see EncodeArmored). It returns
+// ErrFingerprintMismatch if the object was encoded for another instance, and
+// ErrMalformedData if the text is not a single armored block, or if its
+// headers do not describe the decoded object. On error, obj is left unchanged.
+func (ins *Instance) DecodeArmored(text []byte, obj interface{}) error {
+	block, rest := pem.Decode(text)
+	if block == nil || len(bytes.TrimSpace(rest)) != 0 {
+		return fmt.Errorf("%w: not a single armored block", ErrMalformedData)
+	}
+	if block.Headers["Fingerprint"] != ins.Fingerprint() {
+		return ErrFingerprintMismatch
+	}
+	// Keys are put in evaluation form with the ZMultiplier of the instance.
+	cache := zMultiplierCache{instance: ins.zMultiplier}
+	// The object is decoded into a new value, copied to obj once checked.
+	var (
+		decoded interface{}
+		commit  func()
+		err     error
+	)
+	switch o := obj.(type) {
+	case *Ciphertext:
+		res := new(Ciphertext)
+		err = res.UnmarshalBinary(block.Bytes)
+		decoded, commit = res, func() { *o = *res }
+	case *Key:
+		res := new(Key)
+		err = res.unmarshal(block.Bytes, cache)
+		decoded, commit = res, func() { *o = *res }
+	case *PublicKey:
+		res := new(PublicKey)
+		err = res.unmarshal(block.Bytes, cache)
+		decoded, commit = res, func() { *o = *res }
+	case *SecretKey:
+		res := new(SecretKey)
+		err = res.UnmarshalBinary(block.Bytes)
+		decoded, commit = res, func() { *o = *res }
+	case *EvaluationKey:
+		res := new(EvaluationKey)
+		err = res.unmarshal(block.Bytes, cache)
+		decoded, commit = res, func() { *o = *res }
+	case *RotationKeys:
+		res := new(RotationKeys)
+		err = res.unmarshal(block.Bytes, cache)
+		decoded, commit = res, func() { *o = *res }
+	default:
+		return errArmorType(obj)
+	}
+	if err != nil {
+		return err
+	}
+	blockType, headers, err := ins.armorHeaders(decoded)
+	if err != nil {
+		return err
+	}
+	if block.Type != blockType || !sameHeaders(block.Headers, headers) {
+		return fmt.Errorf("%w: armor headers do not describe the object", ErrMalformedData)
+	}
+	commit()
+	return nil
+}
+
+// armorHeaders returns the block type and the headers of obj. It checks that
+// obj matches the instance, so that the fingerprint describes it.
+func (ins *Instance) armorHeaders(obj interface{}) (string, map[string]string, error) {
+	headers := map[string]string{
+		"Version":     strconv.Itoa(SerializationVersion),
+		"Fingerprint": ins.Fingerprint(),
+	}
+	switch o := obj.(type) {
+	case *Ciphertext:
+		if err := ins.CheckCiphertext(o); err != nil {
+			return "", nil, err
+		}
+		headers["Level"] = strconv.Itoa(o.level)
+		headers["Degree"] = strconv.Itoa(o.Degree())
+		headers["Modulus-Bits"] = strconv.Itoa(o.ql.BitLen())
+		return ArmorCiphertext, headers, nil
+	case *Key:
+		var parts []string
+		if o.Public != nil {
+			if err := ins.checkSwitchingPair(o.Public.modulus, o.Public.zm, ins.FirstModulus()); err != nil {
+				return "", nil, err
+			}
+			parts = append(parts, "public")
+		}
+		if o.Secret != nil {
+			if err := ins.checkSecretKey(o.Secret); err != nil {
+				return "", nil, err
+			}
+			parts = append(parts, "secret")
+		}
+		if o.Evaluation != nil {
+			if err := ins.checkEvaluationKey(o.Evaluation); err != nil {
+				return "", nil, err
+			}
+			parts = append(parts, "evaluation")
+		}
+		if o.Conjugation != nil {
+			if err := ins.checkEvaluationKey(o.Conjugation); err != nil {
+				return "", nil, err
+			}
+			parts = append(parts, "conjugation")
+		}
+		headers["Parts"] = strings.Join(parts, ", ")
+		return ArmorKey, headers, nil
+	case *PublicKey:
+		if err := ins.checkSwitchingPair(o.modulus, o.zm, ins.FirstModulus()); err != nil {
+			return "", nil, err
+		}
+		headers["Modulus-Bits"] = strconv.Itoa(o.modulus.BitLen())
+		return ArmorPublicKey, headers, nil
+	case *SecretKey:
+		if err := ins.checkSecretKey(o); err != nil {
+			return "", nil, err
+		}
+		return ArmorSecretKey, headers, nil
+	case *EvaluationKey:
+		if err := ins.checkEvaluationKey(o); err != nil {
+			return "", nil, err
+		}
+		headers["Modulus-Bits"] = strconv.Itoa(o.modulus.BitLen())
+		return ArmorEvaluationKey, headers, nil
+	case *RotationKeys:
+		steps := make([]int, 0, len(o.keys))
+		for k, evk := range o.keys {
+			if err := ins.checkEvaluationKey(evk); err != nil {
+				return "", nil, err
+			}
+			steps = append(steps, k)
+		}
+		sort.Ints(steps)
+		stepStrs := make([]string, len(steps))
+		for i, k := range steps {
+			stepStrs[i] = strconv.Itoa(k)
+		}
+		headers["Steps"] = strings.Join(stepStrs, ", ")
+		return ArmorRotationKeys, headers, nil
+	}
+	return "", nil, errArmorType(obj)
+}
+
+// CheckCiphertext returns ErrFingerprintMismatch if the ciphertext does not
+// belong to the chain of the instance: its dimension must be N, its level at
+// most Depth, its modulus the one of its level, and its primes the ones of its
+// level in RNS mode. Decoded ciphertexts (see Ciphertext.UnmarshalBinary) must
+// be checked before any operation of the instance.
+func (ins *Instance) CheckCiphertext(ciph *Ciphertext) error {
+	var n int
+	switch {
+	case ciph.rns != nil && len(ciph.rns) > 0 && len(ciph.rns[0].Coeffs) > 0:
+		n = len(ciph.rns[0].Coeffs[0])
+	case ciph.rns == nil && len(ciph.c) > 0:
+		n = ciph.c[0].Deg()
+	default:
+		return ErrFingerprintMismatch
+	}
+	if n != ins.N || ciph.level < 0 || ciph.level > ins.Depth || (ciph.rns != nil) != ins.RNS ||
+		ciph.ql == nil || ciph.ql.Cmp(ins.chainOfModuli()[ciph.level]) != 0 {
+		return ErrFingerprintMismatch
+	}
+	if ins.RNS && len(ciph.moduli) != len(ins.rings[ciph.level].Moduli) {
+		return ErrFingerprintMismatch
+	}
+	for i, q := range ciph.moduli {
+		if q != ins.rings[ciph.level].Moduli[i] {
+			return ErrFingerprintMismatch
+		}
+	}
+	return nil
+}
+
+func (ins *Instance) checkSecretKey(sk *SecretKey) error {
+	if len(sk.s.Coeffs) != ins.N {
+		return ErrFingerprintMismatch
+	}
+	return nil
+}
+
+func (ins *Instance) checkEvaluationKey(evk *EvaluationKey) error {
+	if ins.RNS || evk.zm == nil {
+		if !ins.RNS || !ins.matchesDigitKeys(evk) {
+			return ErrFingerprintMismatch
+		}
+		return nil
+	}
+	return ins.checkSwitchingPair(evk.modulus, evk.zm, new(big.Int).Mul(ins.FirstModulus(), ins.pEv))
+}
+
+// checkSwitchingPair returns ErrFingerprintMismatch unless a key modulo
+// `modulus`, in evaluation form with respect to zm, is modulo `want` and can
+// be multiplied with the ZMultiplier of the instance.
+func (ins *Instance) checkSwitchingPair(modulus *big.Int, zm *negacyclic.ZMultiplier, want *big.Int) error {
+	if zm.N != ins.N || zm.Bound.Cmp(ins.zMultiplier.Bound) != 0 || modulus.Cmp(want) != 0 {
+		return ErrFingerprintMismatch
+	}
+	return nil
+}
+
+func sameHeaders(x, y map[string]string) bool {
+	if len(x) != len(y) {
+		return false
+	}
+	for k, v := range x {
+		if w, ok := y[k]; !ok || v != w {
+			return false
+		}
+	}
+	return true
+}
+
+func errArmorType(obj interface{}) error {
+	return fmt.Errorf("cannot armor objects of type %T", obj)
+}
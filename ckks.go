@@ -11,13 +11,14 @@ package ckks
 import (
 	"ckks/negacyclic"
 
+	"math/big"
 	"sync"
 )
 
 // Encrypt encrypts a native plaintext to the given public key.
 func (ins *Instance) Encrypt(pk *PublicKey, p *Plaintext) *Ciphertext {
 	dim := ins.N
-	v := negacyclic.ZO(dim, 0.5).Polynomial()
+	v := ins.zMultiplier.ToNTT(negacyclic.ZO(dim, 0.5).Polynomial())
 	modulus := ins.FirstModulus()
 
 	wg := sync.WaitGroup{}
@@ -26,7 +27,7 @@ func (ins *Instance) Encrypt(pk *PublicKey, p *Plaintext) *Ciphertext {
 
 	go func(wg *sync.WaitGroup) { // c0 = b*v + e0 + m
 		e0 := negacyclic.VectorFromSlice(negacyclic.DG(dim, ins.Sigma)).Polynomial()
-		c0 = ins.zMultiplier.Mul(pk.b, v)
+		c0 = ins.keyProduct(v, pk.b)
 		c0 = negacyclic.Add(c0, e0)
 		c0 = negacyclic.Add(c0, p.m)
 		c0.Mod(modulus)
@@ -35,7 +36,7 @@ func (ins *Instance) Encrypt(pk *PublicKey, p *Plaintext) *Ciphertext {
 
 	go func(wg *sync.WaitGroup) { // c1 = a*v + e1
 		e1 := negacyclic.VectorFromSlice(negacyclic.DG(dim, ins.Sigma)).Polynomial()
-		c1 = ins.zMultiplier.Mul(pk.a, v)
+		c1 = ins.keyProduct(v, pk.a)
 		c1 = negacyclic.Add(c1, e1)
 		c1.Mod(modulus)
 		wg.Done()
@@ -43,20 +44,67 @@ func (ins *Instance) Encrypt(pk *PublicKey, p *Plaintext) *Ciphertext {
 
 	wg.Wait()
 
-	return &Ciphertext{
-		b:     c0,
-		a:     c1,
+	ciph := &Ciphertext{
+		c:     []*negacyclic.Polynomial{c0, c1},
 		level: ins.Depth, // a.k.a. L
 		ql:    modulus,   // a.k.a. qL
+		scale: new(big.Float).Copy(p.scale),
+		nu:    p.bound(),
+		noise: newBound().SetInt(ins.bClean), // see Lemma 1
 	}
+	if ins.RNS {
+		ins.toLimbs(ciph)
+	}
+	return ciph
+}
+
+// EncryptSk encrypts a native plaintext with the given secret key, as
+// (-a*s + e + m, a) mod q_L for a fresh uniform a. Without the term v*pk of
+// public key encryption, the noise is only e (see BcleanSk). The component a
+// is expanded from a fresh seed, which the ciphertext remembers (see
+// Ciphertext.Seed).
+func (ins *Instance) EncryptSk(sk *SecretKey, p *Plaintext) *Ciphertext {
+	dim := ins.N
+	modulus := ins.FirstModulus()
+	seed := negacyclic.NewSeed()
+	a := negacyclic.PolynomialFromSlice(negacyclic.UniformModFromSeed(dim, modulus, seed))
+	e := negacyclic.VectorFromSlice(negacyclic.DG(dim, ins.Sigma))
+	b := negacyclic.MulSimple(a, sk.s) // b: -as + e + m mod q_L
+	b.Negate()
+	b = negacyclic.Add(b, e)
+	b = negacyclic.Add(b, p.m)
+	b.Mod(modulus)
+	a.Mod(modulus)
+
+	ciph := &Ciphertext{
+		c:     []*negacyclic.Polynomial{b, a},
+		level: ins.Depth,
+		ql:    modulus,
+		scale: new(big.Float).Copy(p.scale),
+		nu:    p.bound(),
+		noise: newBound().SetInt(ins.bCleanSk),
+		seed:  seed,
+	}
+	if ins.RNS {
+		ins.toLimbs(ciph)
+	}
+	return ciph
 }
 
-// Decrypt decrypts the ciphertext with the given secret key. It is the user's
-// responsibility to check if the error bounds claimed in c.nu and c.noise are
-// satisfied.
+// Decrypt decrypts the ciphertext with the given secret key. Ciphertexts of
+// degree d are decrypted as c_0 + c_1 s + ... + c_d s^d, with Horner's method.
+// It is the user's responsibility to check that the bounds of c leave enough
+// precision (see Ciphertext.Precision).
 func (ins *Instance) Decrypt(sk *SecretKey, c *Ciphertext) *Plaintext {
-	decrypted := negacyclic.MulSimple(c.a, sk.s)
-	decrypted = negacyclic.Add(decrypted, c.b)
-	decrypted.Mod(c.ql)
-	return &Plaintext{m: decrypted}
+	if ins.RNS {
+		return ins.decryptRNS(sk, c)
+	}
+	d := c.Degree()
+	decrypted := ins.coefficients(c.c[d], c)
+	for k := d - 1; k >= 0; k-- {
+		decrypted = negacyclic.MulSimple(decrypted, sk.s)
+		decrypted = negacyclic.Add(decrypted, ins.coefficients(c.c[k], c))
+		decrypted.Mod(c.ql)
+	}
+	return &Plaintext{m: decrypted, scale: new(big.Float).Copy(c.scale)}
 }
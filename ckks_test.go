@@ -25,6 +25,7 @@ var testInstances = []*testInstance{
 	{"toy", toyParams, nil},
 	{"medium", mediumParams, nil},
 	{"large", largeParams, nil},
+	{"rns", rnsParams, nil},
 }
 
 func TestCKKS(t *testing.T) {
@@ -34,6 +35,7 @@ func TestCKKS(t *testing.T) {
 	for _, ins := range testInstances {
 		ins := ins
 		t.Run(ins.name+"//crypto", func(t *testing.T) { testEncryption(ins.ins, t) })
+		t.Run(ins.name+"//serialization", func(t *testing.T) { testSerialization(ins.ins, t) })
 		t.Run(ins.name+"//encoding", func(t *testing.T) { testEncoding(ins.ins, t) })
 		t.Run(ins.name+"//homomorphic", func(t *testing.T) { testHomomorphic(ins.ins, t) })
 	}
@@ -75,6 +77,15 @@ var (
 		BitLenP: 30,
 		BitLenQ: 155,
 	}
+	rnsParams = &ckks.Parameters{
+		Hamming: 64,
+		N:       1 << 10,
+		Sigma:   3.4,
+		Depth:   2,
+		BitLenP: 30,
+		BitLenQ: 60,
+		RNS:     true,
+	}
 	benchParams = &ckks.Parameters{
 		Hamming: 64,
 		N:       1 << 13,
@@ -3,15 +3,18 @@ package ckks
 import (
 	"math"
 	"math/big"
-	"sync"
+	"math/cmplx"
 
 	"ckks/negacyclic"
 )
 
 // Encode maps the given Complex polynomial following the inverse of the
 // canonical embedding, into a native plaintext of the scheme, i.e., a
-// polynomial in a negacyclic ring. The `delta` parameter controls the error in
-// plaintext operations (see sec. 2.2).
+// polynomial in a negacyclic ring. The i-th slot is the evaluation at
+// ω^(5^i), for ω = exp(iπ/N), and its conjugate at ω^(-5^i), so that the
+// automorphism X -> X^(5^k) rotates the slots by k (see Rotate). The `delta`
+// parameter controls the error in plaintext operations (see sec. 2.2), and is
+// the scale of the plaintext.
 // The canonical embedding needs a primitive 2*N-th Complex root of unity,
 // already precomputed and sanitized in the instance object (see instance.go).
 // Encode returns a non-nil error on malformed input.
@@ -20,9 +23,9 @@ func (ins *Instance) Encode(z []complex128, delta *big.Int) (*Plaintext, error)
 		return nil, ErrBadEncoding
 	}
 	zExpanded := make([]complex128, 2*len(z))
-	for i := 0; i < len(z); i++ {
-		zExpanded[i] = z[i]
-		zExpanded[2*len(z)-1-i] = complex(real(z[i]), -imag(z[i]))
+	for i, j := range ins.slots {
+		zExpanded[j] = z[i]
+		zExpanded[2*len(z)-1-j] = complex(real(z[i]), -imag(z[i]))
 	}
 	pol := VandermondeActionInverse(ins.crtRoots, zExpanded)
 	encoded := negacyclic.NewPolynomial(ins.N)
@@ -32,34 +35,73 @@ func (ins *Instance) Encode(z []complex128, delta *big.Int) (*Plaintext, error)
 		val.Mul(val, bigDelta)
 		encoded.Coeffs[i] = nearestInteger(val)
 	}
-	return &Plaintext{m: encoded}, nil
+	// The canonical embedding norm of the message is delta * max |z_i|, plus
+	// N/2 for the rounding of the coefficients.
+	maxAbs := 0.0
+	for _, zi := range z {
+		maxAbs = math.Max(maxAbs, cmplx.Abs(zi))
+	}
+	nu := newBound().SetFloat64(maxAbs)
+	nu.Mul(nu, bigDelta)
+	nu.Add(nu, big.NewFloat(float64(ins.N)/2))
+	return &Plaintext{m: encoded, scale: newScale(delta), nu: nu}, nil
+}
+
+// Decode applies the canonical embedding on the plaintext polynomial, divided
+// by the scale of the plaintext, to produce a vector with Gaussian integers. It
+// is the inverse of the encoding procedure, for inputs with integer real and
+// imaginary parts (see DecodeComplex for arbitrary inputs).
+func (ins *Instance) Decode(plt *Plaintext) []complex128 {
+	z := ins.DecodeComplex(plt)
+	for i := range z {
+		z[i] = complex(nearestIntegerSmall(real(z[i])), nearestIntegerSmall(imag(z[i])))
+	}
+	return z
 }
 
-// Decode applies the canonical embedding on the plaintext polynomial, to
-// produce a vector with Gaussian integers. It is the inverse of the encoding
-// procedure.
-func (ins *Instance) Decode(plt *Plaintext, delta *big.Int) []complex128 {
+// DecodeComplex applies the canonical embedding on the plaintext polynomial,
+// divided by the scale of the plaintext, and returns the approximate complex
+// values of the slots, without rounding.
+func (ins *Instance) DecodeComplex(plt *Plaintext) []complex128 {
 	N := ins.N
 	zExpanded := make([]complex128, N)
-	bigDelta := new(big.Float).SetInt(delta)
 	for i := range zExpanded {
-		coeff := new(big.Float).SetInt(plt.m.Coeffs[i])
-		coeff.Quo(coeff, bigDelta)
+		coeff := new(big.Float).SetPrec(ScalePrecision).SetInt(plt.m.Coeffs[i])
+		coeff.Quo(coeff, plt.scale)
 		smallCoeff, _ := coeff.Float64()
 		zExpanded[i] = complex(float64(smallCoeff), float64(0))
 	}
 	pol := VandermondeAction(ins.crtRoots, zExpanded)
-
 	z := make([]complex128, N/2)
-	var truncRe, truncIm float64
-	for i := range z {
-		truncRe = nearestIntegerSmall(real(pol[i]))
-		truncIm = nearestIntegerSmall(imag(pol[i]))
-		z[i] = complex(truncRe, truncIm)
+	for i, j := range ins.slots {
+		z[i] = pol[j]
 	}
 	return z
 }
 
+// DecodeReal returns the real parts of the approximate values of the slots
+// (see DecodeComplex), for plaintexts encoding real vectors.
+func (ins *Instance) DecodeReal(plt *Plaintext) []float64 {
+	z := ins.DecodeComplex(plt)
+	x := make([]float64, len(z))
+	for i := range z {
+		x[i] = real(z[i])
+	}
+	return x
+}
+
+// encodeConstant returns the encoding of the vector with all slots equal to c,
+// at the given scale. As X^(N/2) evaluates to i at every ω^(5^k), and to -i at
+// their conjugates, it is ⌊Re(c) scale⌉ + ⌊Im(c) scale⌉ X^(N/2).
+func (ins *Instance) encodeConstant(c complex128, scale *big.Float) *negacyclic.Polynomial {
+	pol := negacyclic.NewPolynomial(ins.N)
+	re := new(big.Float).SetPrec(ScalePrecision).SetFloat64(real(c))
+	im := new(big.Float).SetPrec(ScalePrecision).SetFloat64(imag(c))
+	pol.Coeffs[0] = nearestInteger(re.Mul(re, scale))
+	pol.Coeffs[ins.N/2] = nearestInteger(im.Mul(im, scale))
+	return pol
+}
+
 // nearestInteger returns `⌊x⌉ = ⌊x + .5⌋`, the nearest integer of x.
 func nearestIntegerSmall(x float64) float64{
 	abs := math.Abs(x)
@@ -85,48 +127,64 @@ func nearestInteger(x *big.Float) *big.Int {
 
 // VandermondeActionInverse computes CRT^{-1} * z where CRT is the Vandermonde
 // matrix of the 2N-th primitive roots of unity and N is the dimension of z.
-// It uses the fact that transpose(CRT) * CRT is a the reflect matrix of n * Id.
+// Noticing that CRT^{-1}[i][k] = ω^{-(2k+1)i} / N, for ω = roots[1], it
+// computes a Fast Fourier Transform of z, and multiplies the result by ω^{-i}.
+// This takes O(N log N) operations.
 func VandermondeActionInverse(roots, z []complex128) []complex128 {
 	N := len(z)
-	M := 2 * len(z)
 	res := make([]complex128, N)
-	wg := sync.WaitGroup{}
-	wg.Add(N)
-	for i := 0; i < N; i++ {
-		i := i
-		go func(wg *sync.WaitGroup) {
-			aux := complex(0, 0)
-			for j := 0; j < N; j++ {
-				aux += z[N-1-j] * roots[(2*j+1)*i%M]
-			}
-			res[i] = aux / complex(float64(N), 0)
-			wg.Done()
-		}(&wg)
+	copy(res, z)
+	fft(res, roots, true)
+	scale := complex(float64(N), 0)
+	for i := range res {
+		res[i] *= cmplx.Conj(roots[i]) / scale
 	}
-	wg.Wait()
 	return res
 }
 
 // VandermondeAction computes CRT * z where CRT is the Vandermonde matrix of
-// the 2N-th primitive roots of unity and N is the dimension of z.
+// the 2N-th primitive roots of unity and N is the dimension of z. As
+// CRT[i][j] = ω^{(2i+1)j}, for ω = roots[1], it multiplies z[j] by ω^j and
+// computes a Fast Fourier Transform. This takes O(N log N) operations.
 func VandermondeAction(roots, z []complex128) []complex128 {
-	N := len(z)
-	M := 2 * len(z)
-	res := make([]complex128, N)
-	wg := sync.WaitGroup{}
-	wg.Add(N)
-	for i := 0; i < N; i++ {
-		i := i
-		go func(wg *sync.WaitGroup) {
-			aux := complex(0, 0)
-			for j := 0; j < N; j++ {
-				coeff := z[j] * roots[(2*i+1)*j%M]
-				aux += coeff
-			}
-			res[i] = aux
-			wg.Done()
-		}(&wg)
+	res := make([]complex128, len(z))
+	for j := range z {
+		res[j] = z[j] * roots[j]
 	}
-	wg.Wait()
+	fft(res, roots, false)
 	return res
 }
+
+// fft mutates a with a[i] = sum_j a[j] ω^{2ij}, for ω = roots[1] a primitive
+// 2N-th root of unity and N = len(a) a power of two, or with ω^{-2ij} if
+// inverse is set. It is the iterative radix-2 Cooley-Tukey algorithm, with the
+// twiddle factors read from roots.
+func fft(a, roots []complex128, inverse bool) {
+	n := len(a)
+	for i, j := 1, 0; i < n; i++ {
+		bit := n >> 1
+		for ; j&bit != 0; bit >>= 1 {
+			j ^= bit
+		}
+		j ^= bit
+		if i < j {
+			a[i], a[j] = a[j], a[i]
+		}
+	}
+	for size := 2; size <= n; size <<= 1 {
+		half := size / 2
+		step := 2 * n / size // roots[step] is a primitive size-th root of unity
+		for start := 0; start < n; start += size {
+			for k := 0; k < half; k++ {
+				w := roots[k*step]
+				if inverse {
+					w = cmplx.Conj(w)
+				}
+				u := a[start+k]
+				v := a[start+k+half] * w
+				a[start+k] = u + v
+				a[start+k+half] = u - v
+			}
+		}
+	}
+}
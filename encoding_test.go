@@ -1,7 +1,9 @@
 package ckks_test
 
 import (
+	"math"
 	"math/big"
+	"math/cmplx"
 	"math/rand"
 	"testing"
 
@@ -11,6 +13,7 @@ import (
 
 func testEncodingBasic(t *testing.T) {
 	t.Run("encode_decode_roundtrip_article_example", testEncodeDecodeArticle)
+	t.Run("fft_matches_vandermonde", testFFTMatchesVandermonde)
 }
 
 func testEncoding(ins *ckks.Instance, t *testing.T) {
@@ -20,6 +23,9 @@ func testEncoding(ins *ckks.Instance, t *testing.T) {
 	t.Run("encode_homomorphism", func(t *testing.T) {
 		testEncodeHomomorphism(ins, t)
 	})
+	t.Run("decode_approximate", func(t *testing.T) {
+		testDecodeApproximate(ins, t)
+	})
 }
 
 func testEncodeDecodeArticle(t *testing.T) {
@@ -36,9 +42,11 @@ func testEncodeDecodeArticle(t *testing.T) {
 		panic(err)
 	}
 	delta := big.NewInt(64)
+	// The article evaluates at ω and ω^3, while slots are evaluations at ω and
+	// ω^5 = conj(ω^3): the second slot is conjugated.
 	z := []complex128{
 		complex(3, 4),
-		complex(2, -1),
+		complex(2, 1),
 	}
 	want := []*big.Int{
 		big.NewInt(160),
@@ -58,7 +66,7 @@ func testEncodeDecodeArticle(t *testing.T) {
 			t.Fail()
 		}
 	}
-	decoded := inst.Decode(plt, delta)
+	decoded := inst.Decode(plt)
 	for i := range decoded {
 		if decoded[i] != (z[i]) {
 			t.Fail()
@@ -66,6 +74,18 @@ func testEncodeDecodeArticle(t *testing.T) {
 	}
 }
 
+func testFFTMatchesVandermonde(t *testing.T) {
+	for _, n := range []int{1, 2, 1 << 5, 1 << 10} {
+		roots := rootsOfUnity(2 * n)
+		z := make([]complex128, n)
+		for i := range z {
+			z[i] = complex(rand.NormFloat64(), rand.NormFloat64())
+		}
+		checkClose(ckks.VandermondeAction(roots, z), vandermondeNaive(roots, z, false), t)
+		checkClose(ckks.VandermondeActionInverse(roots, z), vandermondeNaive(roots, z, true), t)
+	}
+}
+
 func testEncodeRoundtrip(inst *ckks.Instance, t *testing.T) {
 	delta := big.NewInt(1 << 31)
 	z := make([]complex128, inst.N/2)
@@ -77,7 +97,7 @@ func testEncodeRoundtrip(inst *ckks.Instance, t *testing.T) {
 	if err != nil {
 		t.Fatal(err)
 	}
-	decoded := inst.Decode(plt, delta)
+	decoded := inst.Decode(plt)
 	for i := range decoded {
 		if decoded[i] != (z[i]) {
 			println(i)
@@ -88,6 +108,32 @@ func testEncodeRoundtrip(inst *ckks.Instance, t *testing.T) {
 	}
 }
 
+func testDecodeApproximate(inst *ckks.Instance, t *testing.T) {
+	delta := big.NewInt(1 << 40)
+	z := make([]complex128, inst.N/2)
+	x := make([]complex128, inst.N/2)
+	for i := range z {
+		z[i] = complex(rand.Float64()-.5, rand.Float64()-.5)
+		x[i] = complex(100*rand.Float64(), 0)
+	}
+	plt, err := inst.Encode(z, delta)
+	if err != nil {
+		t.Fatal(err)
+	}
+	checkCloseWithin(inst.DecodeComplex(plt), z, 1e-6, t)
+
+	plt, err = inst.Encode(x, delta)
+	if err != nil {
+		t.Fatal(err)
+	}
+	decoded := inst.DecodeReal(plt)
+	for i := range x {
+		if math.Abs(decoded[i]-real(x[i])) > 1e-6 {
+			t.Fatalf("got %f, want %f at index %d", decoded[i], real(x[i]), i)
+		}
+	}
+}
+
 func testEncodeHomomorphism(inst *ckks.Instance, t *testing.T) {
 	delta := big.NewInt(1 << 20)
 	z := make([]complex128, inst.N/2)
@@ -115,13 +161,58 @@ func testEncodeHomomorphism(inst *ckks.Instance, t *testing.T) {
 	multiplier := negacyclic.NewZMultiplier(inst.N)
 	r := multiplier.Mul(pltZ.GetPolynomial(), pltW.GetPolynomial())
 	pltProd := ckks.NewPlaintextFromNegacyclic(r)
+	pltProd.SetScale(new(big.Float).Mul(pltZ.Scale(), pltW.Scale()))
 
 	// Decode and compare (notice the scaling factor)
-	delta.Mul(delta, delta)
-	decoded := inst.Decode(pltProd, delta)
+	decoded := inst.Decode(pltProd)
 	checkResult(decoded, h, t)
 }
 
+// vandermondeNaive computes CRT * z, or CRT^{-1} * z if inverse is set, with
+// the O(N^2) matrix-vector product.
+func vandermondeNaive(roots, z []complex128, inverse bool) []complex128 {
+	N := len(z)
+	M := 2 * N
+	res := make([]complex128, N)
+	for i := 0; i < N; i++ {
+		for j := 0; j < N; j++ {
+			if inverse {
+				res[i] += z[N-1-j] * roots[(2*j+1)*i%M]
+			} else {
+				res[i] += z[j] * roots[(2*i+1)*j%M]
+			}
+		}
+		if inverse {
+			res[i] /= complex(float64(N), 0)
+		}
+	}
+	return res
+}
+
+func rootsOfUnity(m int) []complex128 {
+	roots := make([]complex128, m)
+	for i := range roots {
+		roots[i] = ckks.PrimitiveRootOfUnity(i, m)
+	}
+	return roots
+}
+
+func checkClose(got, want []complex128, t *testing.T) {
+	for i := range want {
+		if cmplx.Abs(got[i]-want[i]) > 1e-9*(1+cmplx.Abs(want[i])) {
+			t.Fatalf("got %f, want %f at index %d", got[i], want[i], i)
+		}
+	}
+}
+
+func checkCloseWithin(got, want []complex128, tolerance float64, t *testing.T) {
+	for i := range want {
+		if cmplx.Abs(got[i]-want[i]) > tolerance {
+			t.Fatalf("got %f, want %f at index %d", got[i], want[i], i)
+		}
+	}
+}
+
 var z []complex128
 
 func benchEncoding(b *testing.B) {
@@ -132,6 +223,8 @@ func benchEncoding(b *testing.B) {
 	}
 	b.Run("encode", benchEncode)
 	b.Run("decode", benchDecode)
+	b.Run("vandermonde_naive", benchVandermondeNaive)
+	b.Run("vandermonde_fft", benchVandermondeFFT)
 }
 
 func benchEncode(b *testing.B) {
@@ -145,8 +238,21 @@ func benchEncode(b *testing.B) {
 }
 
 func benchDecode(b *testing.B) {
-	var delta = big.NewInt(1 << 20)
 	for i := 0; i < b.N; i++ {
-		instBench.Decode(bPrecomp.pltxs[0], delta)
+		instBench.Decode(bPrecomp.pltxs[0])
+	}
+}
+
+func benchVandermondeNaive(b *testing.B) {
+	roots := rootsOfUnity(2 * len(z))
+	for i := 0; i < b.N; i++ {
+		vandermondeNaive(roots, z, false)
+	}
+}
+
+func benchVandermondeFFT(b *testing.B) {
+	roots := rootsOfUnity(2 * len(z))
+	for i := 0; i < b.N; i++ {
+		ckks.VandermondeAction(roots, z)
 	}
 }
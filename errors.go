@@ -9,6 +9,15 @@ var (
 	ErrLevelOverflow           = errors.New("homomorphic level overflow")
 	ErrWarningInsecure         = errors.New("warning: insecure parameters")
 	ErrIncompatibleCiphertexts = errors.New("incompatible ciphertexts rescale")
+	ErrScaleMismatch           = errors.New("operands have different scales")
+	ErrMissingRotationKey      = errors.New("no rotation key for the given step")
+	ErrCiphertextDegree        = errors.New("unsupported ciphertext degree")
+	ErrMalformedData           = errors.New("malformed serialized data")
+	ErrUnsupportedVersion      = errors.New("unsupported serialization version")
+	ErrEvaluationForm          = errors.New("cannot serialize polynomials in evaluation form")
+	ErrFingerprintMismatch     = errors.New("object does not belong to the instance")
+	ErrUnknownPreset           = errors.New("unknown preset")
+	ErrNoParameters            = errors.New("no parameters satisfy the requirements")
 )
 
 // ErrBadParameters represent inconsistent parameters when creating an instance.
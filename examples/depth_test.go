@@ -2,6 +2,7 @@ package examples
 
 import (
 	"math/big"
+	"math/cmplx"
 	"math/rand"
 	"testing"
 
@@ -9,7 +10,7 @@ import (
 )
 
 func TestBasicDepth2(t *testing.T) {
-	// These are secure parameters from the original article
+	// These are the parameters of the original article
 	depth := 2
 	params := &ckks.Parameters{
 		Hamming: 64,
@@ -38,6 +39,21 @@ func TestDepth3(t *testing.T) {
 
 }
 
+func TestDepth3Rescale(t *testing.T) {
+	// Rescaling after each product keeps the scale at delta = p, so that q_0
+	// only needs to hold p times the result
+	depth := 3
+	params := &ckks.Parameters{
+		Hamming: 64,
+		N:       1 << 13,
+		Sigma:   3.4,
+		Depth:   depth,
+		BitLenP: 30,
+		BitLenQ: 80,
+	}
+	testDepthRescale(params, t)
+}
+
 func testDepthNoRescale(params *ckks.Parameters, delta *big.Int, t *testing.T) {
 	depth := params.Depth
 	print("Precomputations...")
@@ -91,11 +107,8 @@ func testDepthNoRescale(params *ckks.Parameters, delta *big.Int, t *testing.T) {
 	decrypted := inst.Decrypt(key.Secret, ctx)
 	decrypted.GetPolynomial().Mod(inst.LastModulus())
 
-	// Tune scaling factor
-	for i := 0; i < depth; i++ {
-		delta.Mul(delta, delta)
-	}
-	decoded := inst.Decode(decrypted, delta)
+	// The scaling factor of the result is tracked by the ciphertext
+	decoded := inst.Decode(decrypted)
 	println(" ... OK")
 
 	// Compare some coefficients:
@@ -108,3 +121,59 @@ func testDepthNoRescale(params *ckks.Parameters, delta *big.Int, t *testing.T) {
 		println(decoded[i])
 	}
 }
+
+func testDepthRescale(params *ckks.Parameters, t *testing.T) {
+	depth := params.Depth
+	inst, err := ckks.NewInstance(params)
+	if err != nil && err != ckks.ErrWarningInsecure {
+		t.Fatal(err)
+	}
+	key := inst.GenerateKey()
+
+	z := make([]complex128, inst.N/2)
+	bound := 30
+	want := make([]complex128, inst.N/2)
+	for i := range z {
+		z[i] = complex(float64(rand.Intn(bound)), float64(rand.Intn(bound)))
+		want[i] = z[i]
+		for j := 0; j < depth; j++ {
+			want[i] *= want[i]
+		}
+	}
+	plt, err := inst.Encode(z, inst.GetP())
+	if err != nil {
+		t.Fatal(err)
+	}
+	ctx := inst.Encrypt(key.Public, plt)
+
+	// Each product consumes a level, until none is left
+	for i := 0; i < depth; i++ {
+		ctx, err = inst.MulAndRescale(key.Evaluation, ctx, ctx)
+		if err != nil {
+			t.Fatal(err)
+		}
+		print("level ")
+		println(ctx.Level())
+	}
+	if _, err = inst.MulAndRescale(key.Evaluation, ctx, ctx); err != ckks.ErrLevelOverflow {
+		t.Fatalf("expected ErrLevelOverflow, got %v", err)
+	}
+
+	decoded := inst.Decode(inst.Decrypt(key.Secret, ctx))
+	println("First coefficients of the result:")
+	for i := 0; i < 5; i++ {
+		print("i = ")
+		println(i)
+		print("(original)  ")
+		println(want[i])
+		print("(decrypted) ")
+		println(decoded[i])
+	}
+	// Rescaling rounds away the low bits of the result, and each squaring
+	// doubles the relative error
+	for i := range want {
+		if cmplx.Abs(decoded[i]-want[i]) > 1e-3*cmplx.Abs(want[i])+1 {
+			t.Fatalf("got %v, want %v", decoded[i], want[i])
+		}
+	}
+}
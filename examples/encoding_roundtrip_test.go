@@ -41,7 +41,7 @@ func TestEncodeRoundtrip(t *testing.T) {
 	if err != nil {
 		panic(err)
 	}
-	decoded := inst.Decode(plt, delta)
+	decoded := inst.Decode(plt)
 
 	for i := range decoded {
 		if decoded[i] != z[i] {
@@ -9,7 +9,7 @@ import (
 )
 
 func TestBasicSquare(t *testing.T) {
-	// These are secure parameters from the original article
+	// These are the parameters of the original article
 	params := &ckks.Parameters{
 		Hamming: 64,
 		N:       1 << 13,
@@ -53,9 +53,8 @@ func TestBasicSquare(t *testing.T) {
 	}
 	decrypted := inst.Decrypt(key.Secret, ctxMul)
 
-	// Decode (notice the new scaling factor)
-	delta.Mul(delta, delta)
-	decoded := inst.Decode(decrypted, delta)
+	// Decode (the scaling factor is now delta^2, and tracked by decrypted)
+	decoded := inst.Decode(decrypted)
 
 	// Compare some coefficients:
 	println("First coefficients of the result:")
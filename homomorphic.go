@@ -7,112 +7,370 @@ import (
 	"ckks/negacyclic"
 )
 
-// Add computes the homomorphic addition of c1 and c2. It rescales ciphertexts
-// towards the deeper level if necessary.
-func (ins *Instance) Add(c1, c2 *Ciphertext) *Ciphertext {
+// Add computes the homomorphic addition of c1 and c2, component by component,
+// so that ciphertexts of degree 2 (see MulNoRelin) can be summed before
+// relinearization. It brings the operands to the same level if necessary (see
+// Equalize). It returns ErrScaleMismatch if the scales of the operands differ
+// beyond ScaleTolerance.
+func (ins *Instance) Add(c1, c2 *Ciphertext) (*Ciphertext, error) {
 	ins.Equalize(c1, c2)
-	var aAdd, bAdd *negacyclic.Polynomial
-	wg := sync.WaitGroup{}
-	wg.Add(1)
-	go func(wg *sync.WaitGroup) {
-		aAdd = negacyclic.Add(c1.a, c2.a)
-		aAdd.Mod(c1.ql)
-		wg.Done()
-	}(&wg)
-	wg.Add(1)
-	go func(wg *sync.WaitGroup) {
-		bAdd = negacyclic.Add(c1.b, c2.b)
-		bAdd.Mod(c1.ql)
-		wg.Done()
-	}(&wg)
-	wg.Wait()
+	if !scalesMatch(c1.scale, c2.scale) {
+		return nil, ErrScaleMismatch
+	}
+	if ins.RNS {
+		return addBounds(ins.addRNS(c1, c2), c1, c2), nil
+	}
+	if c1.Degree() < c2.Degree() {
+		c1, c2 = c2, c1
+	}
+	sum := make([]*negacyclic.Polynomial, len(c1.c))
+	parallel(len(sum), func(k int) {
+		sum[k] = ins.coefficients(c1.c[k], c1).Copy()
+		if k < len(c2.c) {
+			sum[k] = negacyclic.Add(sum[k], ins.coefficients(c2.c[k], c2)).Mod(c1.ql)
+		}
+	})
+	return addBounds(c1.withComponents(sum), c1, c2), nil
+}
+
+// Sub computes the homomorphic subtraction c1 - c2. As Add, it brings the
+// operands to the same level if necessary, and returns ErrScaleMismatch if the
+// scales of the operands differ.
+func (ins *Instance) Sub(c1, c2 *Ciphertext) (*Ciphertext, error) {
+	ins.Equalize(c1, c2)
+	return ins.Add(c1, ins.Neg(c2))
+}
+
+// Neg returns a ciphertext that decrypts to the opposite of ciph.
+func (ins *Instance) Neg(ciph *Ciphertext) *Ciphertext {
+	if ins.RNS {
+		return ins.mulIntRNS(ciph, big.NewInt(-1))
+	}
+	neg := make([]*negacyclic.Polynomial, len(ciph.c))
+	for k := range neg {
+		neg[k] = ins.coefficients(ciph.c[k], ciph).Copy()
+		neg[k].Negate()
+		neg[k].Mod(ciph.ql)
+	}
+	return ciph.withComponents(neg)
+}
+
+// AddConst adds the constant c to all the slots of ciph. The constant is
+// encoded at the scale of ciph, so that the result keeps its scale and level.
+func (ins *Instance) AddConst(ciph *Ciphertext, c complex128) *Ciphertext {
+	plt := &Plaintext{m: ins.encodeConstant(c, ciph.scale), scale: ciph.scale}
+	res, _ := ins.AddPlain(ciph, plt) // the scales match
+	return res
+}
+
+// MulConst multiplies all the slots of ciph by the constant c, and rescales the
+// result, so that it is one level deeper than ciph with the same scale. The
+// constant is encoded at the scale removed by rescaling, i.e. p (the last
+// prime of the level in RNS mode), to keep its precision. It returns
+// ErrLevelOverflow if ciph is at level 0 (see MulInt for integer constants).
+func (ins *Instance) MulConst(ciph *Ciphertext, c complex128) (*Ciphertext, error) {
+	if ciph.level == 0 {
+		return nil, ErrLevelOverflow
+	}
+	scale := newScale(ins.rescalingFactor(ciph.level))
+	plt := &Plaintext{m: ins.encodeConstant(c, scale), scale: scale}
+	res := ins.MulPlain(ciph, plt)
+	ins.RS(res, res.level-1)
+	return res, nil
+}
+
+// MulInt multiplies all the slots of ciph by the integer k. The product is
+// exact: it keeps the scale and the level of ciph.
+func (ins *Instance) MulInt(ciph *Ciphertext, k int64) *Ciphertext {
+	var res *Ciphertext
+	if ins.RNS {
+		res = ins.mulIntRNS(ciph, big.NewInt(k))
+	} else {
+		prod := make([]*negacyclic.Polynomial, len(ciph.c))
+		for i := range prod {
+			prod[i] = ins.coefficients(ciph.c[i], ciph).Copy()
+			prod[i].Scale(big.NewInt(k))
+			prod[i].Mod(ciph.ql)
+		}
+		res = ciph.withComponents(prod)
+	}
+	scaleBounds(res, newBound().SetInt(new(big.Int).Abs(big.NewInt(k))))
+	// The noise bound is kept at the rounding floor, so that the precision of
+	// a product by zero stays finite.
+	if res.noise.Cmp(newBound().SetInt(ins.bScale)) < 0 {
+		res.noise = newBound().SetInt(ins.bScale)
+	}
+	return res
+}
+
+// AddPlain computes the homomorphic addition of ciph and plt, reduced modulo
+// the modulus of ciph. It returns ErrScaleMismatch if their scales differ
+// beyond ScaleTolerance.
+func (ins *Instance) AddPlain(ciph *Ciphertext, plt *Plaintext) (*Ciphertext, error) {
+	if !scalesMatch(ciph.scale, plt.scale) {
+		return nil, ErrScaleMismatch
+	}
+	var res *Ciphertext
+	if ins.RNS {
+		res = ins.addPlainRNS(ciph, plt)
+	} else {
+		sum := make([]*negacyclic.Polynomial, len(ciph.c))
+		for k := range sum {
+			sum[k] = ins.coefficients(ciph.c[k], ciph).Copy()
+		}
+		sum[0] = negacyclic.Add(sum[0], plt.m).Mod(ciph.ql)
+		res = ciph.withComponents(sum)
+	}
+	res.nu.Add(res.nu, plt.bound())
+	return res, nil
+}
 
-	return &Ciphertext{
-		a:     aAdd,
-		b:     bAdd,
-		level: c1.level,
-		ql:    c1.ql,
+// MulPlain computes a ciphertext that decrypts to the negacyclic product of
+// ciph and plt, whose scale is the product of their scales. It stays at the
+// level of ciph, and needs no evaluation key.
+func (ins *Instance) MulPlain(ciph *Ciphertext, plt *Plaintext) *Ciphertext {
+	if ins.RNS {
+		return scaleBounds(ins.mulPlainRNS(ciph, plt), plt.bound())
 	}
+	m := ins.multipliers[ciph.level]
+	pt := m.ToNTT(plt.m.Copy().Mod(ciph.ql))
+	prod := make([]*negacyclic.Polynomial, len(ciph.c))
+	parallel(len(prod), func(k int) {
+		prod[k] = m.FromNTT(m.Hadamard(m.ToNTT(ciph.c[k]), pt)).Mod(m.PQ).Mod(ciph.ql)
+	})
+	res := ciph.withComponents(prod)
+	res.scale.Mul(ciph.scale, plt.scale)
+	return scaleBounds(res, plt.bound())
 }
 
 // Mul computes a ciphertext that decrypts to the negacyclic product of c1 and
-// c2. It rescales ciphertexts towards the deeper level if necessary.
+// c2, whose scale is the product of their scales. It is the relinearization of
+// their tensor product (see MulNoRelin and Relinearize), and returns
+// ErrCiphertextDegree if the product has degree above 2.
 func (ins *Instance) Mul(evk *EvaluationKey, c1, c2 *Ciphertext) (*Ciphertext, error) {
+	return ins.Relinearize(evk, ins.MulNoRelin(c1, c2))
+}
+
+// MulNoRelin computes the tensor product of c1 and c2, whose degree is the sum
+// of their degrees, e.g. (b1b2, a1b2 + a2b1, a1a2) for ciphertexts of degree
+// 1, that decrypts with s^2. Its scale is the product of their scales. It
+// brings the operands to the same level if necessary (see Equalize). Each
+// operand is transformed to evaluation form only once, and not at all if it is
+// already in evaluation form (see ToNTT).
+func (ins *Instance) MulNoRelin(c1, c2 *Ciphertext) *Ciphertext {
 	ins.Equalize(c1, c2)
-	level := c1.level
+	if ins.RNS {
+		return mulBounds(ins.mulNoRelinRNS(c1, c2), c1, c2)
+	}
 	modulus := c1.ql
+	m := ins.multipliers[c1.level]
 
-	wg := sync.WaitGroup{}
-	wg.Add(2)
-
-	var d0, d1, d2 *negacyclic.Polynomial // (b1b2, a1b2 + a2b1, a1a2) (mod ql)
-
-	go func(wg *sync.WaitGroup) {
-		d0 = ins.multiplier.Mul(c1.b, c2.b).Mod(modulus)
-		wg.Done()
-	}(&wg)
-	go func(wg *sync.WaitGroup) {
-		d1 = ins.multiplier.Mul(c1.a, c2.b)
-		aux := ins.multiplier.Mul(c2.a, c1.b)
-		d1 = negacyclic.Add(d1, aux).Mod(modulus)
-		wg.Done()
-	}(&wg)
-
-	d2 = ins.multiplier.Mul(c1.a, c2.a)
-	d2.Mod(modulus)
+	x := make([]*negacyclic.Polynomial, len(c1.c))
+	parallel(len(x), func(i int) { x[i] = m.ToNTT(c1.c[i]) })
+	y := x
+	if c2 != c1 {
+		y = make([]*negacyclic.Polynomial, len(c2.c))
+		parallel(len(y), func(j int) { y[j] = m.ToNTT(c2.c[j]) })
+	}
 
-	var d2evkA, d2evkB *negacyclic.Polynomial // ⌊p^{-1} d2 evk⌉ (mod ql)
-	var nearestA, nearestB *negacyclic.Polynomial
-	wg.Add(2)
-	go func(wg *sync.WaitGroup) {
-		d2evkA = ins.zMultiplier.Mul(d2, evk.a)
-		nearestA = d2evkA.ScaleNearest(ins.pEv)
-		wg.Done()
-	}(&wg)
-	go func(wg *sync.WaitGroup) {
-		d2evkB = ins.zMultiplier.Mul(d2, evk.b)
-		nearestB = d2evkB.ScaleNearest(ins.pEv)
-		wg.Done()
-	}(&wg)
-	wg.Wait()
+	// The sums of products are exact in (-Q/2, Q/2] before reduction modulo ql.
+	d := make([]*negacyclic.Polynomial, len(x)+len(y)-1) // d_k = sum_{i+j=k} x_i y_j
+	parallel(len(d), func(k int) {
+		for i := range x {
+			if j := k - i; j >= 0 && j < len(y) {
+				if d[k] == nil {
+					d[k] = m.Hadamard(x[i], y[j])
+				} else {
+					d[k] = negacyclic.Add(d[k], m.Hadamard(x[i], y[j])).Mod(m.PQ)
+				}
+			}
+		}
+		d[k] = m.FromNTT(d[k]).Mod(m.PQ).Mod(modulus)
+	})
+	res := c1.withComponents(d)
+	res.scale.Mul(c1.scale, c2.scale)
+	return mulBounds(res, c1, c2)
+}
 
-	aMul := negacyclic.Add(d1, nearestA).Mod(modulus)
-	bMul := negacyclic.Add(d0, nearestB).Mod(modulus)
+// Relinearize returns a ciphertext of degree 1 that decrypts as ciph, by
+// switching its component of s^2 to s with the evaluation key. Ciphertexts of
+// degree 1 are cloned, and ciphertexts of degree above 2 are rejected with
+// ErrCiphertextDegree.
+func (ins *Instance) Relinearize(evk *EvaluationKey, ciph *Ciphertext) (*Ciphertext, error) {
+	switch ciph.Degree() {
+	case 1:
+		return ciph.Clone(), nil
+	case 2:
+	default:
+		return nil, ErrCiphertextDegree
+	}
+	if ins.RNS {
+		return ins.keySwitchBounds(ins.relinearizeRNS(evk, ciph)), nil
+	}
+	d0, d1 := ins.coefficients(ciph.c[0], ciph), ins.coefficients(ciph.c[1], ciph)
+	nearestA, nearestB := ins.switchKey(evk, ins.coefficients(ciph.c[2], ciph)) // ⌊P^{-1} d2 evk⌉
+	return ins.keySwitchBounds(ciph.withComponents([]*negacyclic.Polynomial{
+		negacyclic.Add(d0, nearestB).Mod(ciph.ql),
+		negacyclic.Add(d1, nearestA).Mod(ciph.ql),
+	})), nil
+}
 
-	c := &Ciphertext{
-		a:     aMul,
-		b:     bMul,
-		level: level,
-		ql:    modulus,
+// MulAndRescale computes the product of c1 and c2 (see Mul), and rescales it
+// by p (by the last prime of its level in RNS mode), dividing its scale by the
+// same factor. The result is one level deeper than the operands, once
+// equalized. It returns ErrLevelOverflow if the operands are at level 0.
+func (ins *Instance) MulAndRescale(evk *EvaluationKey, c1, c2 *Ciphertext) (*Ciphertext, error) {
+	ins.Equalize(c1, c2)
+	if c1.level == 0 {
+		return nil, ErrLevelOverflow
 	}
+	c, err := ins.Mul(evk, c1, c2)
+	if err != nil {
+		return nil, err
+	}
+	ins.RS(c, c.level-1)
 	return c, nil
 }
 
-// Equalize scales the upper-level ciphertext to the level of the deeper
-// ciphertext. It mutates the concerned ciphertext.
+// Equalize brings the upper-level ciphertext down to the level of the deeper
+// ciphertext, by reducing it modulo the smaller modulus. Unlike rescaling (see
+// RS), this keeps its scale, so that circuits can combine ciphertexts of
+// different levels without tracking levels by hand. It mutates the concerned
+// ciphertext.
 func (ins *Instance) Equalize(c1, c2 *Ciphertext) {
-	if c1.level == c2.level {
+	if c1.level > c2.level {
+		ins.dropLevel(c1, c2.level)
+	} else {
+		ins.dropLevel(c2, c1.level)
+	}
+}
+
+// dropLevel reduces the ciphertext modulo q_level, which divides its modulus,
+// so that it still decrypts to the same message, at the same scale and with the
+// same bounds. It does nothing if the ciphertext is already deeper than or at
+// the level. The reduced component a is no longer expanded from the seed of
+// ciph, which is dropped.
+func (ins *Instance) dropLevel(ciph *Ciphertext, level int) {
+	if ciph.level <= level {
 		return
 	}
-	if c1.level < c2.level {
-		ins.RS(c1, c2.level)
-	} else {
-		ins.RS(c2, c1.level)
+	ciph.seed = nil
+	modulus := ins.chainOfModuli()[level]
+	if ins.RNS {
+		ins.dropLevelRNS(ciph, level)
 	}
+	for k := range ciph.c {
+		ciph.c[k] = ins.coefficients(ciph.c[k], ciph).Copy().Mod(modulus)
+	}
+	ciph.level = level
+	ciph.ql = modulus
 }
 
-// RS scales the ciphertext to the intended level. It does nothing if the
-// ciphertext is already deeper than or at the level.
+// RS scales the ciphertext to the intended level, dividing its scale by the
+// same factor. It does nothing if the ciphertext is already deeper than or at
+// the level. In RNS mode, it divides by the primes of the dropped levels. The
+// bounds of the message and the noise are updated (see noise.go). The
+// rescaled component a is no longer expanded from the seed of ciph, which is
+// dropped.
 func (ins *Instance) RS(ciph *Ciphertext, level int) {
 	if ciph.level <= level {
 		return
 	}
+	ciph.seed = nil
+	if ins.RNS {
+		ins.rescaleRNS(ciph, level)
+		return
+	}
 	offset := level - ciph.level // l' - l
 	// denom = p ^ {l - l'}
 	denom := new(big.Int).Exp(ins.p, big.NewInt(int64(-offset)), nil)
 	modulus := new(big.Int).Div(ciph.ql, denom)
-	ciph.a = ciph.a.ScaleNearest(denom).Mod(modulus)
-	ciph.b = ciph.b.ScaleNearest(denom).Mod(modulus)
+	for k := range ciph.c {
+		ciph.c[k] = ins.coefficients(ciph.c[k], ciph).ScaleNearest(denom).Mod(modulus)
+	}
+	ins.rescaleBounds(ciph, denom)
 	ciph.level = level
+	ciph.scale = rescaledScale(ciph.scale, ciph.ql, modulus)
 	ciph.ql = modulus
 }
+
+// switchKey returns ⌊P^{-1} d swk⌉, the polynomials to add to a ciphertext to
+// switch the key of its term d, from the target of swk to the secret key (see
+// switchingKey). The coefficients of d lie in (-q_L/2, q_L/2].
+func (ins *Instance) switchKey(swk *EvaluationKey, d *negacyclic.Polynomial) (*negacyclic.Polynomial, *negacyclic.Polynomial) {
+	dNTT := ins.zMultiplier.ToNTT(d)
+	var nearestA, nearestB *negacyclic.Polynomial
+	wg := sync.WaitGroup{}
+	wg.Add(2)
+	go func(wg *sync.WaitGroup) {
+		nearestA = ins.keyProduct(dNTT, swk.a).ScaleNearest(ins.pEv)
+		wg.Done()
+	}(&wg)
+	go func(wg *sync.WaitGroup) {
+		nearestB = ins.keyProduct(dNTT, swk.b).ScaleNearest(ins.pEv)
+		wg.Done()
+	}(&wg)
+	wg.Wait()
+	return nearestA, nearestB
+}
+
+// ToNTT puts the ciphertext in evaluation form, so that it is not transformed
+// again when multiplied (see Mul). This pays off when the same ciphertext is
+// multiplied several times. It mutates the ciphertext.
+func (ins *Instance) ToNTT(ciph *Ciphertext) {
+	if ins.RNS {
+		ring := ins.rings[ciph.level]
+		for k := range ciph.rns {
+			ciph.rns[k] = ring.ToNTT(ciph.rns[k])
+		}
+		return
+	}
+	m := ins.multipliers[ciph.level]
+	for k := range ciph.c {
+		ciph.c[k] = m.ToNTT(ciph.c[k])
+	}
+}
+
+// FromNTT puts the ciphertext back in coefficient form. It mutates the
+// ciphertext.
+func (ins *Instance) FromNTT(ciph *Ciphertext) {
+	if ins.RNS {
+		ring := ins.rings[ciph.level]
+		for k := range ciph.rns {
+			ciph.rns[k] = ring.FromNTT(ciph.rns[k])
+		}
+		return
+	}
+	for k := range ciph.c {
+		ciph.c[k] = ins.coefficients(ciph.c[k], ciph)
+	}
+}
+
+// rescalingFactor returns q_l / q_{l-1}, the factor removed by rescaling a
+// ciphertext of level l by one level.
+func (ins *Instance) rescalingFactor(level int) *big.Int {
+	if ins.RNS {
+		return new(big.Int).SetUint64(ins.rings[level].Moduli[level])
+	}
+	return ins.p
+}
+
+// rescaledScale returns scale * newModulus / oldModulus.
+func rescaledScale(scale *big.Float, oldModulus, newModulus *big.Int) *big.Float {
+	res := new(big.Float).SetPrec(ScalePrecision).SetInt(newModulus)
+	res.Mul(res, scale)
+	return res.Quo(res, new(big.Float).SetInt(oldModulus))
+}
+
+// parallel calls f(0), ..., f(count-1) concurrently, and waits for them.
+func parallel(count int, f func(i int)) {
+	wg := sync.WaitGroup{}
+	wg.Add(count)
+	for i := 0; i < count; i++ {
+		go func(i int) {
+			f(i)
+			wg.Done()
+		}(i)
+	}
+	wg.Wait()
+}
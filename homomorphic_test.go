@@ -1,7 +1,9 @@
 package ckks_test
 
 import (
+	"math"
 	"math/big"
+	"math/cmplx"
 	"testing"
 
 	"ckks"
@@ -46,7 +48,21 @@ func testHomomorphic(ins *ckks.Instance, t *testing.T) {
 func testHomomorphicOps(ins *ckks.Instance, t *testing.T) {
 	t.Run("addition", func(t *testing.T) { testAdd(ins, t) })
 	t.Run("rescale", func(t *testing.T) { testRS(ins, t) })
+	t.Run("scale_tracking", func(t *testing.T) { testScaleTracking(ins, t) })
+	t.Run("multiplication_evaluation_form", func(t *testing.T) { testMulNTT(ins, t) })
 	t.Run("multiplication", func(t *testing.T) { testMul(ins, t) })
+	t.Run("multiplication_rescale", func(t *testing.T) { testMulAndRescale(ins, t) })
+	t.Run("mixed_levels", func(t *testing.T) { testMixedLevels(ins, t) })
+	t.Run("equalize", func(t *testing.T) { testEqualize(ins, t) })
+	t.Run("rotation", func(t *testing.T) { testRotate(ins, t) })
+	t.Run("conjugation", func(t *testing.T) { testConjugate(ins, t) })
+	t.Run("plaintext_addition", func(t *testing.T) { testAddPlain(ins, t) })
+	t.Run("plaintext_multiplication", func(t *testing.T) { testMulPlain(ins, t) })
+	t.Run("subtraction_negation", func(t *testing.T) { testSubNeg(ins, t) })
+	t.Run("constants", func(t *testing.T) { testConstants(ins, t) })
+	t.Run("noise_tracking", func(t *testing.T) { testNoiseTracking(ins, t) })
+	t.Run("integer_multiplication", func(t *testing.T) { testMulInt(ins, t) })
+	t.Run("lazy_relinearization", func(t *testing.T) { testLazyRelinearization(ins, t) })
 }
 
 func testAdd(inst *ckks.Instance, t *testing.T) {
@@ -61,11 +77,14 @@ func testAdd(inst *ckks.Instance, t *testing.T) {
 	}
 
 	// Homomorphic add
-	cipherAdd := inst.Add(ciphertext0, ciphertext1)
+	cipherAdd, err := inst.Add(ciphertext0, ciphertext1)
+	if err != nil {
+		t.Fatal(err)
+	}
 
 	// Decrypt the addition and check result
 	decrypted := inst.Decrypt(key.Secret, cipherAdd)
-	decoded := inst.Decode(decrypted, precompHomBasic.delta)
+	decoded := inst.Decode(decrypted)
 	checkResult(decoded, msgAddition, t)
 }
 
@@ -82,11 +101,51 @@ func testRS(inst *ckks.Instance, t *testing.T) {
 	}
 }
 
+func testScaleTracking(inst *ckks.Instance, t *testing.T) {
+	key := precompHomBasic.key
+	ct := precompHomBasic.ciphs[0].Clone()
+	delta := new(big.Float).SetInt(precompHomBasic.delta)
+	if ct.Scale().Cmp(delta) != 0 {
+		t.Fatal("fresh ciphertexts should have the scale of their plaintext")
+	}
+
+	prod, err := inst.Mul(key.Evaluation, ct, ct)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if prod.Scale().Cmp(new(big.Float).Mul(delta, delta)) != 0 {
+		t.Fatal("multiplication should multiply the scales")
+	}
+
+	// Rescaling divides the scale by the dropped modulus
+	oldMod := new(big.Float).SetInt(prod.Modulus())
+	inst.RS(prod, prod.Level()-1)
+	want := new(big.Float).Mul(delta, delta)
+	want.Mul(want, new(big.Float).SetInt(prod.Modulus())).Quo(want, oldMod)
+	if !closeFloats(prod.Scale(), want) {
+		t.Fatalf("got scale %s, want %s", prod.Scale().Text('g', 20), want.Text('g', 20))
+	}
+
+	// Scales delta^2 / p and delta disagree
+	if _, err = inst.Add(prod, ct.Clone()); err != ckks.ErrScaleMismatch {
+		t.Fatalf("expected ErrScaleMismatch, got %v", err)
+	}
+	if decrypted := inst.Decrypt(key.Secret, prod); decrypted.Scale().Cmp(prod.Scale()) != 0 {
+		t.Fatal("decryption should keep the scale")
+	}
+}
+
+func closeFloats(x, y *big.Float) bool {
+	diff := new(big.Float).Sub(x, y)
+	diff.Quo(diff, y)
+	f, _ := diff.Float64()
+	return math.Abs(f) < 1e-12
+}
+
 func testMul(inst *ckks.Instance, t *testing.T) {
 	key := precompHomBasic.key
 	msgs := precompHomBasic.msgs
 	ciphs := precompHomBasic.ciphs
-	delta := precompHomBasic.delta
 	msgProd := make([]complex128, len(msgs[0]))
 	for i := range msgs[0] {
 		msgProd[i] = msgs[0][i] * msgs[1][i]
@@ -98,29 +157,414 @@ func testMul(inst *ckks.Instance, t *testing.T) {
 		t.Fatal(err)
 	}
 	// Rescale
-	inst.RS(cipherProd, cipherProd.Level() - 1)
+	inst.RS(cipherProd, cipherProd.Level()-1)
 
-	// Decrypt the product and check result
+	// Decrypt the product and check result; rescaling divided its scale.
 	decrypted := inst.Decrypt(key.Secret, cipherProd)
-	decPol := decrypted.GetPolynomial()
-	// Rescale produces an encryption of m/p, therefore multiply by p.
-	for _, coeff := range decPol.Coeffs {
-		coeff.Mul(coeff, inst.GetP())
+	decoded := inst.Decode(decrypted)
+	checkResult(decoded, msgProd, t)
+}
+
+func testMulAndRescale(inst *ckks.Instance, t *testing.T) {
+	key := precompHomBasic.key
+	msgs := precompHomBasic.msgs
+	ciph := precompHomBasic.ciphs[0].Clone()
+	msgProd := make([]complex128, len(msgs[0]))
+	for i := range msgs[0] {
+		msgProd[i] = msgs[0][i] * msgs[1][i]
 	}
 
-	delta.Mul(delta, delta)
-	decoded := inst.Decode(decrypted, delta)
+	prod, err := inst.MulAndRescale(key.Evaluation, ciph, precompHomBasic.ciphs[1])
+	if err != nil {
+		t.Fatal(err)
+	}
+	if prod.Level() != ciph.Level()-1 {
+		t.Fatalf("got level %d, want %d", prod.Level(), ciph.Level()-1)
+	}
+	// The scale is divided by the dropped factor of the modulus
+	want := new(big.Float).Mul(ciph.Scale(), precompHomBasic.ciphs[1].Scale())
+	want.Mul(want, new(big.Float).SetInt(prod.Modulus()))
+	want.Quo(want, new(big.Float).SetInt(ciph.Modulus()))
+	if !closeFloats(prod.Scale(), want) {
+		t.Fatalf("got scale %s, want %s", prod.Scale().Text('g', 20), want.Text('g', 20))
+	}
+	decoded := inst.Decode(inst.Decrypt(key.Secret, prod))
 	checkResult(decoded, msgProd, t)
+
+	// No level is left below level 0
+	inst.RS(ciph, 0)
+	if _, err = inst.MulAndRescale(key.Evaluation, ciph, ciph); err != ckks.ErrLevelOverflow {
+		t.Fatalf("expected ErrLevelOverflow, got %v", err)
+	}
+}
+
+func testMixedLevels(inst *ckks.Instance, t *testing.T) {
+	key := precompHomBasic.key
+	msgs := precompHomBasic.msgs
+	// At the scale p, products are rescaled back to about the same scale.
+	ciphs := make([]*ckks.Ciphertext, len(msgs))
+	for i, msg := range msgs {
+		plt, err := inst.Encode(msg, inst.GetP())
+		if err != nil {
+			t.Fatal(err)
+		}
+		ciphs[i] = inst.Encrypt(key.Public, plt)
+	}
+	prod, err := inst.MulAndRescale(key.Evaluation, ciphs[0], ciphs[1])
+	if err != nil {
+		t.Fatal(err)
+	}
+	msgSum := make([]complex128, len(msgs[0]))
+	msgProd := make([]complex128, len(msgs[0]))
+	for i := range msgs[0] {
+		msgSum[i] = msgs[0][i] + msgs[0][i]*msgs[1][i]
+		msgProd[i] = msgs[0][i] * msgs[0][i] * msgs[1][i]
+	}
+
+	// The fresh operand is brought down to the level of the product
+	sum, err := inst.Add(ciphs[0].Clone(), prod)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if sum.Level() != prod.Level() {
+		t.Fatalf("got level %d, want %d", sum.Level(), prod.Level())
+	}
+	checkResult(inst.Decode(inst.Decrypt(key.Secret, sum)), msgSum, t)
+
+	mixed, err := inst.Mul(key.Evaluation, prod, ciphs[0].Clone())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if mixed.Level() != prod.Level() {
+		t.Fatalf("got level %d, want %d", mixed.Level(), prod.Level())
+	}
+	checkResult(inst.Decode(inst.Decrypt(key.Secret, mixed)), msgProd, t)
+}
+
+// Equalize drops the upper operand to the deeper level, keeping its scale.
+func testEqualize(inst *ckks.Instance, t *testing.T) {
+	key := precompHomBasic.key
+	msg := precompHomBasic.msgs[0]
+	for _, upperFirst := range []bool{true, false} {
+		upper := precompHomBasic.ciphs[0].Clone()
+		lower := precompHomBasic.ciphs[1].Clone()
+		inst.RS(lower, lower.Level()-1)
+		lowerScale := lower.Scale()
+		if upperFirst {
+			inst.Equalize(upper, lower)
+		} else {
+			inst.Equalize(lower, upper)
+		}
+		if upper.Level() != lower.Level() || lower.Level() != inst.Depth-1 {
+			t.Fatalf("got levels %d and %d, want %d", upper.Level(), lower.Level(), inst.Depth-1)
+		}
+		if upper.Scale().Cmp(precompHomBasic.ciphs[0].Scale()) != 0 || lower.Scale().Cmp(lowerScale) != 0 {
+			t.Fatal("Equalize should keep the scales of its operands")
+		}
+		checkResult(inst.Decode(inst.Decrypt(key.Secret, upper)), msg, t)
+	}
+}
+
+func testRotate(inst *ckks.Instance, t *testing.T) {
+	key := precompHomBasic.key
+	msg := precompHomBasic.msgs[0]
+	slots := len(msg)
+	rtk := inst.GenerateRotationKeys(key.Secret, []int{1, 3, -1})
+	for _, k := range []int{1, 3, -1, 0} {
+		rotated, err := inst.Rotate(rtk, precompHomBasic.ciphs[0], k)
+		if err != nil {
+			t.Fatal(err)
+		}
+		want := make([]complex128, slots)
+		for i := range want {
+			want[i] = msg[((i+k)%slots+slots)%slots]
+		}
+		decoded := inst.Decode(inst.Decrypt(key.Secret, rotated))
+		checkResult(decoded, want, t)
+	}
+
+	if _, err := inst.Rotate(rtk, precompHomBasic.ciphs[0], 2); err != ckks.ErrMissingRotationKey {
+		t.Fatalf("expected ErrMissingRotationKey, got %v", err)
+	}
+}
+
+func testConjugate(inst *ckks.Instance, t *testing.T) {
+	key := precompHomBasic.key
+	msg := precompHomBasic.msgs[0]
+	conj, err := inst.Conjugate(key.Conjugation, precompHomBasic.ciphs[0])
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := make([]complex128, len(msg))
+	for i := range msg {
+		want[i] = cmplx.Conj(msg[i])
+	}
+	decoded := inst.Decode(inst.Decrypt(key.Secret, conj))
+	checkResult(decoded, want, t)
+
+	// (z + conj(z)) / 2 extracts the real parts
+	sum, err := inst.Add(precompHomBasic.ciphs[0].Clone(), conj)
+	if err != nil {
+		t.Fatal(err)
+	}
+	re := inst.DecodeReal(inst.Decrypt(key.Secret, sum))
+	for i := range msg {
+		if math.Abs(re[i]/2-real(msg[i])) > 1e-3 {
+			t.Fatalf("got %f, want %f", re[i]/2, real(msg[i]))
+		}
+	}
+}
+
+func testAddPlain(inst *ckks.Instance, t *testing.T) {
+	key := precompHomBasic.key
+	msgs := precompHomBasic.msgs
+	msgSum := make([]complex128, len(msgs[0]))
+	for i := range msgs[0] {
+		msgSum[i] = msgs[0][i] + msgs[1][i]
+	}
+	sum, err := inst.AddPlain(precompHomBasic.ciphs[0], precompHomBasic.pltxs[1])
+	if err != nil {
+		t.Fatal(err)
+	}
+	decoded := inst.Decode(inst.Decrypt(key.Secret, sum))
+	checkResult(decoded, msgSum, t)
+
+	// Scales delta and 1 disagree
+	plt := ckks.NewPlaintextFromNegacyclic(precompHomBasic.pltxs[1].GetPolynomial())
+	if _, err = inst.AddPlain(precompHomBasic.ciphs[0], plt); err != ckks.ErrScaleMismatch {
+		t.Fatalf("expected ErrScaleMismatch, got %v", err)
+	}
+}
+
+func testMulPlain(inst *ckks.Instance, t *testing.T) {
+	key := precompHomBasic.key
+	msgs := precompHomBasic.msgs
+	msgProd := make([]complex128, len(msgs[0]))
+	for i := range msgs[0] {
+		msgProd[i] = msgs[0][i] * msgs[1][i]
+	}
+	prod := inst.MulPlain(precompHomBasic.ciphs[0], precompHomBasic.pltxs[1])
+	if prod.Level() != precompHomBasic.ciphs[0].Level() {
+		t.Fatal("plaintext multiplication should not change the level")
+	}
+	decoded := inst.Decode(inst.Decrypt(key.Secret, prod))
+	checkResult(decoded, msgProd, t)
+
+	// The product can be rescaled as any other
+	inst.RS(prod, prod.Level()-1)
+	decoded = inst.Decode(inst.Decrypt(key.Secret, prod))
+	checkResult(decoded, msgProd, t)
+}
+
+func testSubNeg(inst *ckks.Instance, t *testing.T) {
+	key := precompHomBasic.key
+	msgs := precompHomBasic.msgs
+	msgDiff := make([]complex128, len(msgs[0]))
+	msgNeg := make([]complex128, len(msgs[0]))
+	for i := range msgs[0] {
+		msgDiff[i] = msgs[0][i] - msgs[1][i]
+		msgNeg[i] = -msgs[0][i]
+	}
+	diff, err := inst.Sub(precompHomBasic.ciphs[0].Clone(), precompHomBasic.ciphs[1].Clone())
+	if err != nil {
+		t.Fatal(err)
+	}
+	checkResult(inst.Decode(inst.Decrypt(key.Secret, diff)), msgDiff, t)
+	neg := inst.Neg(precompHomBasic.ciphs[0])
+	checkResult(inst.Decode(inst.Decrypt(key.Secret, neg)), msgNeg, t)
+}
+
+func testConstants(inst *ckks.Instance, t *testing.T) {
+	key := precompHomBasic.key
+	msg := precompHomBasic.msgs[0]
+	ciph := precompHomBasic.ciphs[0]
+	c := complex(1, -2)
+	msgSum := make([]complex128, len(msg))
+	for i := range msg {
+		msgSum[i] = msg[i] + c
+	}
+	sum := inst.AddConst(ciph, c)
+	if sum.Scale().Cmp(ciph.Scale()) != 0 || sum.Level() != ciph.Level() {
+		t.Fatal("adding a constant should keep the scale and the level")
+	}
+	checkResult(inst.Decode(inst.Decrypt(key.Secret, sum)), msgSum, t)
+
+	c = complex(3.7, 0.25)
+	msgProd := make([]complex128, len(msg))
+	for i := range msg {
+		msgProd[i] = msg[i] * c
+	}
+	prod, err := inst.MulConst(ciph, c)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if prod.Level() != ciph.Level()-1 || !closeFloats(prod.Scale(), ciph.Scale()) {
+		t.Fatal("multiplying by a constant should consume a level and keep the scale")
+	}
+	checkCloseWithin(inst.DecodeComplex(inst.Decrypt(key.Secret, prod)), msgProd, 1e-3, t)
+
+	bottom := ciph.Clone()
+	inst.RS(bottom, 0)
+	if _, err = inst.MulConst(bottom, c); err != ckks.ErrLevelOverflow {
+		t.Fatalf("expected ErrLevelOverflow, got %v", err)
+	}
+}
+
+func testNoiseTracking(inst *ckks.Instance, t *testing.T) {
+	key := precompHomBasic.key
+	msgs := precompHomBasic.msgs
+	c0, c1 := precompHomBasic.ciphs[0], precompHomBasic.ciphs[1]
+	nu := new(big.Float).SetInt(precompHomBasic.delta)
+	nu.Mul(nu, big.NewFloat(maxAbs(msgs[0])))
+	if c0.MessageBound().Cmp(nu) < 0 {
+		t.Fatalf("message bound %s below %s", c0.MessageBound().Text('g', 5), nu.Text('g', 5))
+	}
+	checkPrecision(inst, key, c0, msgs[0], t)
+
+	msgProd := make([]complex128, len(msgs[0]))
+	msgSum := make([]complex128, len(msgs[0]))
+	for i := range msgs[0] {
+		msgProd[i] = msgs[0][i] * msgs[1][i]
+		msgSum[i] = msgs[0][i] + msgs[1][i]
+	}
+	sum, err := inst.Add(c0, c1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	checkPrecision(inst, key, sum, msgSum, t)
+	prod, err := inst.Mul(key.Evaluation, c0, c1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if prod.Precision() >= c0.Precision() {
+		t.Fatal("multiplication should consume precision")
+	}
+	checkPrecision(inst, key, prod, msgProd, t)
+	checkNoise(inst, key, prod, msgProd, t)
+	rescaled, err := inst.MulAndRescale(key.Evaluation, c0, c1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	checkPrecision(inst, key, rescaled, msgProd, t)
+	checkNoise(inst, key, rescaled, msgProd, t)
+	if rescaled.NoiseBound().Cmp(prod.NoiseBound()) >= 0 {
+		t.Fatal("rescaling should divide the noise")
+	}
+
+	// Once the message may wrap around the modulus, no precision is left.
+	grown := c0
+	for i := 0; i < 10 && !math.IsInf(grown.Precision(), -1); i++ {
+		grown = inst.MulInt(grown, 1<<62)
+	}
+	if !math.IsInf(grown.Precision(), -1) {
+		t.Fatalf("expected no precision left, got %f bits", grown.Precision())
+	}
+}
+
+func testMulInt(inst *ckks.Instance, t *testing.T) {
+	key := precompHomBasic.key
+	msg := precompHomBasic.msgs[0]
+	ciph := precompHomBasic.ciphs[0]
+	msgProd := make([]complex128, len(msg))
+	for i := range msg {
+		msgProd[i] = msg[i] * -7
+	}
+	prod := inst.MulInt(ciph, -7)
+	if prod.Scale().Cmp(ciph.Scale()) != 0 || prod.Level() != ciph.Level() {
+		t.Fatal("integer multiplication should keep the scale and the level")
+	}
+	checkResult(inst.Decode(inst.Decrypt(key.Secret, prod)), msgProd, t)
+
+	zero := inst.MulInt(ciph, 0)
+	if math.IsInf(zero.Precision(), 0) || zero.NoiseBound().Sign() == 0 {
+		t.Fatalf("unexpected precision of %f bits for a product by zero", zero.Precision())
+	}
+	checkResult(inst.Decode(inst.Decrypt(key.Secret, zero)), make([]complex128, len(msg)), t)
+}
+
+func testLazyRelinearization(inst *ckks.Instance, t *testing.T) {
+	key := precompHomBasic.key
+	msgs := precompHomBasic.msgs
+	c0, c1 := precompHomBasic.ciphs[0], precompHomBasic.ciphs[1]
+	msgInner := make([]complex128, len(msgs[0]))
+	msgCube := make([]complex128, len(msgs[0]))
+	for i := range msgs[0] {
+		msgInner[i] = msgs[0][i]*msgs[1][i] + msgs[1][i]*msgs[1][i]
+		msgCube[i] = msgs[0][i] * msgs[0][i] * msgs[1][i]
+	}
+
+	// Sum of products, relinearized once
+	prod0 := inst.MulNoRelin(c0, c1)
+	prod1 := inst.MulNoRelin(c1, c1)
+	if prod0.Degree() != 2 {
+		t.Fatalf("got degree %d, want 2", prod0.Degree())
+	}
+	inner, err := inst.Add(prod0, prod1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	checkResult(inst.Decode(inst.Decrypt(key.Secret, inner)), msgInner, t)
+	relin, err := inst.Relinearize(key.Evaluation, inner)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if relin.Degree() != 1 {
+		t.Fatalf("got degree %d, want 1", relin.Degree())
+	}
+	checkResult(inst.Decode(inst.Decrypt(key.Secret, relin)), msgInner, t)
+
+	// Degree 3 decrypts with s^3, but cannot be relinearized
+	cube := inst.MulNoRelin(inst.MulNoRelin(c0, c0), c1)
+	if cube.Degree() != 3 {
+		t.Fatalf("got degree %d, want 3", cube.Degree())
+	}
+	checkResult(inst.Decode(inst.Decrypt(key.Secret, cube)), msgCube, t)
+	if _, err = inst.Relinearize(key.Evaluation, cube); err != ckks.ErrCiphertextDegree {
+		t.Fatalf("expected ErrCiphertextDegree, got %v", err)
+	}
+}
+
+func testMulNTT(inst *ckks.Instance, t *testing.T) {
+	key := precompHomBasic.key
+	ciph := precompHomBasic.ciphs[0].Clone()
+	want, err := inst.Mul(key.Evaluation, ciph, ciph)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Multiplying ciphertexts in evaluation form gives the same result
+	inst.ToNTT(ciph)
+	got, err := inst.Mul(key.Evaluation, ciph, precompHomBasic.ciphs[0])
+	if err != nil {
+		t.Fatal(err)
+	}
+	wantPol := inst.Decrypt(key.Secret, want).GetPolynomial()
+	gotPol := inst.Decrypt(key.Secret, got).GetPolynomial()
+	for i := range wantPol.Coeffs {
+		if wantPol.Coeffs[i].Cmp(gotPol.Coeffs[i]) != 0 {
+			t.Fatal("products in coefficient and evaluation forms differ")
+		}
+	}
+
+	// The ciphertext in evaluation form still decrypts correctly
+	inst.FromNTT(ciph)
+	decrypted := inst.Decrypt(key.Secret, ciph)
+	decoded := inst.Decode(decrypted)
+	checkResult(decoded, precompHomBasic.msgs[0], t)
 }
 
 func benchHomomorphic(b *testing.B) {
 	b.Run("addition", benchHomAdd)
 	b.Run("multiplication", benchHomMul)
+	b.Run("rotation", benchHomRotate)
 }
 
 func benchHomAdd(b *testing.B) {
+	var err error
 	for i := 0; i < b.N; i++ {
-		instBench.Add(bPrecomp.ciphs[0], bPrecomp.ciphs[0])
+		if _, err = instBench.Add(bPrecomp.ciphs[0], bPrecomp.ciphs[0]); err != nil {
+			panic(err)
+		}
 	}
 }
 
@@ -135,6 +579,18 @@ func benchHomMul(b *testing.B) {
 	}
 }
 
+func benchHomRotate(b *testing.B) {
+	var err error
+	rtk := instBench.GenerateRotationKeys(bPrecomp.key.Secret, []int{1})
+	ciph := bPrecomp.ciphs[0]
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		if _, err = instBench.Rotate(rtk, ciph, 1); err != nil {
+			panic(err)
+		}
+	}
+}
+
 func checkResult(got, want []complex128, t *testing.T) {
 	for i := range want {
 		if got[i] != want[i] {
@@ -142,3 +598,39 @@ func checkResult(got, want []complex128, t *testing.T) {
 		}
 	}
 }
+
+// checkPrecision checks that the slots of ciph are within 2^-Precision of want.
+func checkPrecision(inst *ckks.Instance, key *ckks.Key, ciph *ckks.Ciphertext, want []complex128, t *testing.T) {
+	precision := ciph.Precision()
+	if precision <= 0 || math.IsInf(precision, 0) {
+		t.Fatalf("unexpected precision of %f bits", precision)
+	}
+	got := inst.DecodeComplex(inst.Decrypt(key.Secret, ciph))
+	for i := range want {
+		if err := cmplx.Abs(got[i] - want[i]); err > math.Exp2(-precision) {
+			t.Fatalf("slot %d: error %g exceeds the bound 2^-%f", i, err, precision)
+		}
+	}
+}
+
+// checkNoise checks that the error of the slots of ciph, at its scale, is
+// within its noise bound.
+func checkNoise(inst *ckks.Instance, key *ckks.Key, ciph *ckks.Ciphertext, want []complex128, t *testing.T) {
+	got := inst.DecodeComplex(inst.Decrypt(key.Secret, ciph))
+	diff := make([]complex128, len(want))
+	for i := range want {
+		diff[i] = got[i] - want[i]
+	}
+	measured := new(big.Float).Mul(big.NewFloat(maxAbs(diff)), ciph.Scale())
+	if measured.Cmp(ciph.NoiseBound()) > 0 {
+		t.Fatalf("measured noise %s exceeds the bound %s", measured.Text('g', 5), ciph.NoiseBound().Text('g', 5))
+	}
+}
+
+func maxAbs(z []complex128) float64 {
+	res := 0.0
+	for _, zi := range z {
+		res = math.Max(res, cmplx.Abs(zi))
+	}
+	return res
+}
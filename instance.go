@@ -1,6 +1,8 @@
 package ckks
 
 import (
+	"crypto/sha256"
+	"encoding/hex"
 	"fmt"
 	"math"
 	"math/big"
@@ -19,46 +21,133 @@ type Instance struct {
 
 	// Encoding:
 	crtRoots []complex128 // complex128 primitive Mth roots of unity.
+	slots    []int        // slot j is the evaluation at crtRoots[2*slots[j]+1]
 
 	// Noise handling:
-	bClean *big.Int // Bound of the noise of clean ciphertexts (Lemma 1).
-	bScale *big.Int // Additive noise of rescaling.
+	bClean   *big.Int // Bound of the noise of clean ciphertexts (Lemma 1).
+	bCleanSk *big.Int // The same, for secret key encryption.
+	bScale   *big.Int // Additive noise of rescaling.
+
+	security SecurityEstimate // Cost of the attacks (see security.go)
 
 	// Negacyclic ring arithmetic
-	multiplier  *negacyclic.CRTMultiplier // modulo p*q
-	zMultiplier *negacyclic.ZMultiplier   // integer
+	multipliers []*negacyclic.CRTMultiplier // exact products at each level
+	zMultiplier *negacyclic.ZMultiplier     // exact products with keys
+	rings       []*negacyclic.RNSRing       // modulo q_0 * ... * q_l, RNS mode
+	keyRings    []*negacyclic.RNSRing       // modulo q_0 * ... * q_l * P, RNS mode
 }
 
 // NewInstance sets the given parameters and performs precomputations, after
 // input is sanitized. An error is returned in case the parameters are
 // insecure, and the user is responsible of accepting/rejecting the instance.
 func NewInstance(params *Parameters) (*Instance, error) {
+	// p, q are chosen to be two RLWE primes with p << q.
+	moduli := &Moduli{
+		P:  negacyclic.RLWEPrime(params.BitLenP, 2*params.N),
+		Q0: negacyclic.RLWEPrime(params.BitLenQ, 2*params.N),
+	}
+
+	// In RNS mode, the chain is q_0 * q_1 * ... * q_L, for distinct primes q_l
+	// close to p, and p is set to q_1.
+	if params.RNS {
+		if params.BitLenP > negacyclic.MaxRNSModulusBitLen || params.BitLenQ > negacyclic.MaxRNSModulusBitLen {
+			return nil, ErrBadParameters("RNS primes should have at most 60 bits")
+		}
+		primes, err := rnsPrimes(params.N, params.Depth, params.BitLenP, moduli.Q0)
+		if err != nil {
+			return nil, err
+		}
+		moduli.RNS = primes
+		if params.Depth > 0 {
+			moduli.P = new(big.Int).SetUint64(moduli.RNS[1])
+		}
+		// Keys are switched prime by prime, so that P only needs to be as
+		// large as the primes of the chain (see rns.go).
+		if moduli.PEv, err = rnsSpecialPrime(params, primes); err != nil {
+			return nil, err
+		}
+		return newInstance(params, moduli)
+	}
+
+	// It suffices to assume that P is approximately equal to q_L.
+	bitsPEval := params.BitLenP*params.Depth + params.BitLenQ
+	moduli.PEv = negacyclic.RLWEPrime(bitsPEval, 2*params.N)
+	return newInstance(params, moduli)
+}
+
+// NewInstanceWithModuli is as NewInstance, with the given moduli instead of
+// the ones derived from the bit lengths of the parameters, e.g. the moduli
+// published by another party (see Instance.ParameterSet). It returns an
+// ErrBadParameters error if the moduli are inconsistent with the parameters.
+func NewInstanceWithModuli(params *Parameters, moduli *Moduli) (*Instance, error) {
+	if err := moduli.check(params); err != nil {
+		return nil, err
+	}
+	return newInstance(params, moduli.Copy())
+}
+
+// NewInstanceFromParameterSet returns NewInstanceWithModuli(&ps.Parameters,
+// &ps.Moduli).
+func NewInstanceFromParameterSet(ps *ParameterSet) (*Instance, error) {
+	return NewInstanceWithModuli(&ps.Parameters, &ps.Moduli)
+}
+
+// ParameterSet returns the parameters of the instance, and its moduli. An
+// identical instance is obtained with NewInstanceFromParameterSet.
+func (ins *Instance) ParameterSet() *ParameterSet {
+	moduli := &Moduli{P: ins.p, Q0: ins.q0, PEv: ins.pEv}
+	if ins.RNS {
+		moduli.RNS = ins.rings[ins.Depth].Moduli
+	}
+	return &ParameterSet{Parameters: ins.Parameters, Moduli: *moduli.Copy()}
+}
+
+// newInstance performs the precomputations of an instance with the given
+// moduli. The roots of unity of the number theoretic transforms are
+// determined by the primes, so that the instance only depends on its
+// parameters and moduli.
+func newInstance(params *Parameters, m *Moduli) (*Instance, error) {
 	crtRoots := make([]complex128, 2*params.N)
 	for i := 0; i < 2*params.N; i++ {
 		crtRoots[i] = PrimitiveRootOfUnity(i, 2*params.N)
 	}
 
-	// p, q are chosen to be two RLWE primes with p << q.
-	p := negacyclic.RLWEPrime(params.BitLenP, 2*params.N)
-	q0 := negacyclic.RLWEPrime(params.BitLenQ, 2*params.N)
+	var moduli []*big.Int
+	var multipliers []*negacyclic.CRTMultiplier
+	var rings, keyRings []*negacyclic.RNSRing
+	if params.RNS {
+		rings = newRNSChain(params.N, m.RNS)
+		keyRings = newKeyRings(params.N, m.RNS, m.PEv.Uint64())
+		moduli = rnsChainOfModuli(rings)
+	} else {
+		moduli = chainOfModuli(params.Depth, m.P, m.Q0)
+		var err error
+		if multipliers, err = newLevelMultipliers(params.N, moduli); err != nil {
+			return nil, err
+		}
+	}
 
-	// It suffices to assume that P is approximately equal to q_L.
-	bitsPEval := params.BitLenP*params.Depth + params.BitLenQ
-	pEval := negacyclic.RLWEPrime(bitsPEval, 2*params.N)
-	multiplier := negacyclic.NewCRTMultiplier(params.N, p, q0)
-	zMultiplier := negacyclic.NewZMultiplier(params.N)
+	// Keys live in evaluation form with respect to a ZMultiplier, whose primes
+	// are chosen once for operands bounded by P * q_L.
+	bound := new(big.Int).Mul(moduli[params.Depth], m.PEv)
+	zMultiplier := negacyclic.NewBoundedZMultiplier(params.N, bound)
 
 	inst := &Instance{
 		Parameters:  *params,
-		p:           p,
-		q0:          q0,
-		pEv:         pEval,
+		p:           m.P,
+		q0:          m.Q0,
+		pEv:         m.PEv,
 		crtRoots:    crtRoots,
+		slots:       slotIndices(params.N),
 		bClean:      computeBclean(params.Sigma, params.N, params.Hamming),
+		bCleanSk:    computeBcleanSk(params.Sigma, params.N),
 		bScale:      computeBscale(params.N, params.Hamming),
-		multiplier:  multiplier,
+		multipliers: multipliers,
 		zMultiplier: zMultiplier,
+		rings:       rings,
+		keyRings:    keyRings,
 	}
+	inst.security = inst.estimateSecurity()
 	err := inst.Sanitize()
 	if err != nil && err != ErrWarningInsecure {
 		return nil, err
@@ -80,14 +169,30 @@ func (ins *Instance) String() string {
 	str += "  Complex primitive M-th root of unity: "
 	str += fmt.Sprint(ins.crtRoots[1]) + "\n"
 	str += "  Moduli:" + modStr
+	str += "  Security: " + fmt.Sprintf("%.1f", ins.SecurityLevel()) + " bits\n"
 	str += "\n\n----- END PARAMETERS ----- \n"
 	return str
 }
 
+// GetP returns p, the prime of the chain q_0 * p^l. In RNS mode, it returns
+// q_1.
 func (ins *Instance) GetP() *big.Int {
 	return ins.p
 }
 
+// Fingerprint identifies the resolved parameter set of the instance: it is the
+// hexadecimal prefix of a SHA-256 digest of the parameters, p, P and the chain
+// of moduli. Two instances with the same fingerprint handle the same keys and
+// ciphertexts (see EncodeArmored).
+func (ins *Instance) Fingerprint() string {
+	h := sha256.New()
+	fmt.Fprintf(h, "%d %d %d %g %t\n", ins.N, ins.Depth, ins.Hamming, ins.Sigma, ins.RNS)
+	for _, x := range append([]*big.Int{ins.p, ins.pEv}, ins.chainOfModuli()...) {
+		fmt.Fprintln(h, x)
+	}
+	return hex.EncodeToString(h.Sum(nil)[:16])
+}
+
 // Sanitize performs sanity-checks and correctness checks on the given instance.
 func (ins *Instance) Sanitize() error {
 	if (ins.N == 0) || ((ins.N & (ins.N - 1)) != 0) {
@@ -96,7 +201,7 @@ func (ins *Instance) Sanitize() error {
 	if ins.N < ins.Hamming {
 		return ErrBadParameters("hamming weight is incompatible with ring")
 	}
-	if ins.N < 1<<8 || ins.Hamming < 64 {
+	if ins.SecurityLevel() < ins.MinimumSecurity() {
 		return ErrWarningInsecure
 	}
 	return nil
@@ -107,6 +212,14 @@ func (ins *Instance) Bclean() *big.Int {
 	return ins.bClean
 }
 
+// BcleanSk represents the error introduced by secret key encryption on a level
+// L ciphertext (see EncryptSk). The noise is a single Gaussian polynomial e,
+// bounded by 6σ√N in the canonical embedding, whereas public key encryption
+// adds v*e + e_0 + e_1*s, which accounts for the other terms of Bclean.
+func (ins *Instance) BcleanSk() *big.Int {
+	return ins.bCleanSk
+}
+
 // Distance returns the default distance of x,y.
 func (ins *Instance) Distance(x, y *negacyclic.Polynomial) *big.Int {
 	return ins.L1Distance(x, y)
@@ -118,18 +231,27 @@ func (ins *Instance) L1Distance(x, y *negacyclic.Polynomial) *big.Int {
 }
 
 // BMul computes the noise estimation of multiplied ciphertexts at level `l`.
+// In RNS mode, the polynomial to switch is decomposed by prime (see rns.go),
+// and the noise is proportional to q_0 + ... + q_l rather than q_l.
 func (ins *Instance) BMul(modulus *big.Int) *big.Int {
 	// See Lemma 3 (Addition/Multiplication)
-	bKs := float64(8) * ins.Sigma * float64(ins.N) / math.Sqrt(3)
-	result := big.NewInt(int64(bKs))
-	result.Mul(result, modulus)
+	result := computeBks(ins.Sigma, ins.N)
+	if ins.RNS {
+		result.Mul(result, ins.digitsBound(modulus))
+	} else {
+		result.Mul(result, modulus)
+	}
 	result.Quo(result, ins.pEv)
 	result.Add(result, ins.bScale)
 	return result
 }
 
-// FirstModulus returns `q_0 * p^L`, the modulus of fresh ciphertexts.
+// FirstModulus returns `q_0 * p^L`, the modulus of fresh ciphertexts. In RNS
+// mode, it returns `q_0 * q_1 * ... * q_L`.
 func (ins *Instance) FirstModulus() *big.Int {
+	if ins.RNS {
+		return new(big.Int).Set(ins.rings[ins.Depth].Q)
+	}
 	mod := new(big.Int).Exp(ins.p, big.NewInt(int64(ins.Depth)), nil)
 	return mod.Mul(mod, ins.q0)
 }
@@ -153,6 +275,16 @@ func computeBclean(sigma float64, dim, hamming int) *big.Int {
 	return big.NewInt(int64(bClean))
 }
 
+// See Lemma 1: the bound of the term e_0 alone.
+func computeBcleanSk(sigma float64, dim int) *big.Int {
+	return big.NewInt(int64(6 * sigma * math.Sqrt(float64(dim))))
+}
+
+// See Lemma 3: the noise of key switching is bKs * q_l / P.
+func computeBks(sigma float64, dim int) *big.Int {
+	return big.NewInt(int64(8 * sigma * float64(dim) / math.Sqrt(3)))
+}
+
 // See Lemma 2 (Rescaling).
 func computeBscale(dim, hamming int) *big.Int {
 	N := float64(dim)
@@ -161,12 +293,65 @@ func computeBscale(dim, hamming int) *big.Int {
 	return big.NewInt(int64(bScale))
 }
 
+// keyProduct returns the product in Z[X]/(X^N+1) of x and a key polynomial y,
+// both in evaluation form with respect to the instance ZMultiplier.
+func (ins *Instance) keyProduct(x, y *negacyclic.RNSPolynomial) *negacyclic.Polynomial {
+	zm := ins.zMultiplier
+	return zm.FromNTT(zm.Hadamard(x, y))
+}
+
+// newLevelMultipliers returns, for each modulus q_l of the chain, a
+// CRTMultiplier modulo a product of word-sized primes exceeding 2N * q_l^2.
+// Products of polynomials with coefficients in (-q_l/2, q_l/2], and sums of
+// two such products, are thus computed exactly, and reduced modulo q_l
+// afterwards. All levels share the primes of the top level, dropping the ones
+// they do not need. It returns an ErrBadParameters error if there are not
+// enough word-sized primes for the top level.
+func newLevelMultipliers(n int, moduli []*big.Int) ([]*negacyclic.CRTMultiplier, error) {
+	counts := make([]int, len(moduli))
+	for l, ql := range moduli {
+		bound := new(big.Int).Mul(ql, ql)
+		bound.Mul(bound, big.NewInt(int64(2*n)))
+		// Each prime has MaxRNSModulusBitLen bits, i.e. is larger than 2^59.
+		// A CRTMultiplier needs at least two primes.
+		counts[l] = bound.BitLen()/(negacyclic.MaxRNSModulusBitLen-1) + 2
+	}
+	words, err := negacyclic.FindRNSPrimes(negacyclic.MaxRNSModulusBitLen, 2*n, counts[len(moduli)-1])
+	if err != nil {
+		return nil, ErrBadParameters("not enough word-sized primes for the products of the chain")
+	}
+	primes := make([]*big.Int, len(words))
+	for i, q := range words {
+		primes[i] = new(big.Int).SetUint64(q)
+	}
+	top := negacyclic.NewCRTMultiplier(n, primes...)
+	multipliers := make([]*negacyclic.CRTMultiplier, len(moduli))
+	for l := range moduli {
+		multipliers[l] = top.Prefix(counts[l])
+	}
+	return multipliers, nil
+}
+
+// coefficients returns the coefficient form modulo q_l of a polynomial of the
+// given ciphertext.
+func (ins *Instance) coefficients(x *negacyclic.Polynomial, ciph *Ciphertext) *negacyclic.Polynomial {
+	if !x.IsNTT() {
+		return x
+	}
+	m := ins.multipliers[ciph.level]
+	return m.FromNTT(x).Mod(m.PQ).Mod(ciph.ql)
+}
+
 func (ins *Instance) chainOfModuli() []*big.Int {
-	l := ins.Depth
-	q0 := ins.q0
-	p := ins.p
+	if ins.RNS {
+		return rnsChainOfModuli(ins.rings)
+	}
+	return chainOfModuli(ins.Depth, ins.p, ins.q0)
+}
+
+func chainOfModuli(l int, p, q0 *big.Int) []*big.Int {
 	res := make([]*big.Int, l+1)
-	res[0] = q0
+	res[0] = new(big.Int).Set(q0)
 	for i := 1; i <= l; i++ {
 		res[i] = new(big.Int)
 		res[i].Mul(res[i-1], p)
@@ -174,6 +359,17 @@ func (ins *Instance) chainOfModuli() []*big.Int {
 	return res
 }
 
+// slotIndices returns the indices j of the roots ω^(2j+1) = ω^(5^k) for
+// k = 0, ..., N/2-1, so that the automorphism X -> X^(5^k) rotates the slots
+// (see Rotate).
+func slotIndices(n int) []int {
+	slots := make([]int, n/2)
+	for k := range slots {
+		slots[k] = (negacyclic.GaloisElement(k, n) - 1) / 2
+	}
+	return slots
+}
+
 func PrimitiveRootOfUnity(index, n int) complex128 {
 	exp := math.Pi * 2 * float64(index) / float64(n)
 	return complex(math.Cos(exp), math.Sin(exp))
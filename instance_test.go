@@ -1,14 +1,27 @@
 package ckks_test
 
 import (
+	"encoding/json"
+	"math"
+	"math/big"
 	"testing"
 
 	"ckks"
+	"ckks/negacyclic"
 )
 
 func testParameters(t *testing.T) {
 	t.Run("bad_instance", sanitizeBadInstance)
 	t.Run("insecure_instance", sanitizeInsecureInstance)
+	t.Run("security_estimate", testSecurityEstimate)
+	t.Run("minimum_security", testMinimumSecurity)
+	t.Run("bad_rns_instance", sanitizeBadRNSInstance)
+	t.Run("rns_chain", testRNSChain)
+	t.Run("parameter_set", testParameterSet)
+	t.Run("bad_moduli", testBadModuli)
+	t.Run("explicit_P", testExplicitP)
+	t.Run("presets", testPresets)
+	t.Run("planner", testPlanner)
 }
 
 func sanitizeBadInstance(t *testing.T) {
@@ -38,11 +51,253 @@ func sanitizeInsecureInstance(t *testing.T) {
 	}
 }
 
+func testSecurityEstimate(t *testing.T) {
+	// Maximal moduli for 128 bits of security in the homomorphic encryption
+	// standard, with ternary secrets and errors of standard deviation 3.2.
+	for _, c := range []struct{ n, logQ int }{
+		{1 << 10, 27}, {1 << 11, 54}, {1 << 12, 109}, {1 << 13, 218}, {1 << 14, 438}, {1 << 15, 881},
+	} {
+		level := ckks.EstimateSecurity(c.n, float64(c.logQ), 3.2, 2*c.n/3).Level()
+		if level < 120 || level > 136 {
+			t.Errorf("N = %d, log q = %d: got %.1f bits, want about 128", c.n, c.logQ, level)
+		}
+	}
+
+	prev := math.Inf(1)
+	for logQ := 100; logQ <= 800; logQ += 100 {
+		level := ckks.EstimateSecurity(1<<14, float64(logQ), 3.2, 64).Level()
+		if level > prev {
+			t.Fatalf("security should decrease with the modulus, got %.1f then %.1f", prev, level)
+		}
+		prev = level
+	}
+
+	est := ckks.EstimateSecurity(1<<13, 218, 3.2, 64)
+	if est.Hybrid > est.PrimalUSVP {
+		t.Errorf("the hybrid attack should exploit sparse secrets, got %+v", est)
+	}
+}
+
+func testMinimumSecurity(t *testing.T) {
+	inst, err := ckks.NewInstance(toyParams)
+	if err != ckks.ErrWarningInsecure {
+		t.Fatal("expected a warning on toy parameters")
+	}
+	if inst.MinimumSecurity() != ckks.DefaultMinimumSecurity {
+		t.Fatalf("got minimum security %g, want the default", inst.MinimumSecurity())
+	}
+	params := *toyParams
+	params.MinSecurity = math.Floor(inst.SecurityLevel())
+	lenient, err := ckks.NewInstance(&params)
+	if err != nil {
+		t.Fatalf("expected no warning above the minimum security, got %v", err)
+	}
+	if lenient.Sanitize() != nil {
+		t.Fatal("Sanitize should use the minimum security of the instance")
+	}
+	// The minimum of one instance does not apply to the others
+	if inst.Sanitize() != ckks.ErrWarningInsecure {
+		t.Fatal("expected a warning on toy parameters")
+	}
+}
+
+func sanitizeBadRNSInstance(t *testing.T) {
+	params := *rnsParams
+	params.BitLenQ = 61 // Primes must fit in a word
+	inst, err := ckks.NewInstance(&params)
+	if err == nil {
+		t.Error("Expected an error, got nil")
+	}
+	if inst != nil {
+		t.Error("Expected nil instance, but got an instance.")
+	}
+
+	// There are fewer than 41 primes of 20 bits equal to 1 mod 2^13
+	short := ckks.Parameters{N: 1 << 12, Depth: 40, BitLenP: 20, BitLenQ: 60, Hamming: 64, Sigma: 3.4, RNS: true}
+	if _, err := ckks.NewInstance(&short); err == nil || err == ckks.ErrWarningInsecure {
+		t.Errorf("Expected ErrBadParameters, got %v", err)
+	}
+}
+
+func testRNSChain(t *testing.T) {
+	inst, err := ckks.NewInstance(rnsParams)
+	if err != nil && err != ckks.ErrWarningInsecure {
+		t.Fatal(err)
+	}
+	key := inst.GenerateKey()
+	plt, err := inst.Encode(make([]complex128, inst.N/2), big.NewInt(1))
+	if err != nil {
+		t.Fatal(err)
+	}
+	ct := inst.Encrypt(key.Public, plt)
+	if ct.Modulus().Cmp(inst.FirstModulus()) != 0 {
+		t.Fatal("fresh ciphertexts should be modulo q_L")
+	}
+	seen := make(map[string]bool)
+	for level := inst.Depth - 1; level >= 0; level-- {
+		oldMod := ct.Modulus()
+		inst.RS(ct, level)
+		prime, rem := new(big.Int).QuoRem(oldMod, ct.Modulus(), new(big.Int))
+		if rem.Sign() != 0 || !prime.ProbablyPrime(32) {
+			t.Fatal("rescaling should divide the modulus by a prime")
+		}
+		if prime.BitLen() != inst.BitLenP || seen[prime.String()] {
+			t.Fatalf("unexpected prime %s in the chain", prime)
+		}
+		seen[prime.String()] = true
+	}
+	if ct.Modulus().Cmp(inst.LastModulus()) != 0 {
+		t.Fatal("the last modulus should be q_0")
+	}
+
+	// Keys are switched with a word-sized special prime, outside the chain.
+	pEv := inst.ParameterSet().Moduli.PEv
+	if pEv.BitLen() != inst.BitLenQ || seen[pEv.String()] || pEv.Cmp(inst.LastModulus()) == 0 {
+		t.Fatalf("unexpected special prime %s", pEv)
+	}
+}
+
 func benchPrecomputations(b *testing.B) {
 	var err error
 	for i := 0; i < b.N; i++ {
-		if _, err = ckks.NewInstance(benchParams); err != nil {
+		if _, err = ckks.NewInstance(benchParams); err != nil && err != ckks.ErrWarningInsecure {
 			panic(err)
 		}
 	}
 }
+
+func testParameterSet(t *testing.T) {
+	for _, params := range []*ckks.Parameters{toyParams, rnsParams} {
+		inst, err := ckks.NewInstance(params)
+		if err != nil && err != ckks.ErrWarningInsecure {
+			t.Fatal(err)
+		}
+		ps := inst.ParameterSet()
+
+		text, err := json.Marshal(ps)
+		if err != nil {
+			t.Fatal(err)
+		}
+		fromJSON := new(ckks.ParameterSet)
+		if err := json.Unmarshal(text, fromJSON); err != nil {
+			t.Fatal(err)
+		}
+		data, err := ps.MarshalBinary()
+		if err != nil {
+			t.Fatal(err)
+		}
+		fromBinary := new(ckks.ParameterSet)
+		if err := fromBinary.UnmarshalBinary(data); err != nil {
+			t.Fatal(err)
+		}
+
+		key := inst.GenerateKey()
+		armored, err := inst.EncodeArmored(key)
+		if err != nil {
+			t.Fatal(err)
+		}
+		for _, decoded := range []*ckks.ParameterSet{fromJSON, fromBinary} {
+			other, err := ckks.NewInstanceFromParameterSet(decoded)
+			if err != nil && err != ckks.ErrWarningInsecure {
+				t.Fatal(err)
+			}
+			if other.Fingerprint() != inst.Fingerprint() {
+				t.Fatal("reconstructed instance should have the same fingerprint")
+			}
+			// Keys of the original instance are keys of the reconstructed one.
+			if err := other.DecodeArmored(armored, new(ckks.Key)); err != nil {
+				t.Fatal(err)
+			}
+		}
+
+		var pars ckks.Parameters
+		data, err = params.MarshalBinary()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if err := pars.UnmarshalBinary(data); err != nil || pars != *params {
+			t.Fatalf("got %v, want %v (error %v)", pars, *params, err)
+		}
+	}
+}
+
+func testBadModuli(t *testing.T) {
+	inst, err := ckks.NewInstance(rnsParams)
+	if err != nil && err != ckks.ErrWarningInsecure {
+		t.Fatal(err)
+	}
+	for name, edit := range map[string]func(m *ckks.Moduli){
+		"composite_p":   func(m *ckks.Moduli) { m.PEv.Add(m.PEv, big.NewInt(2)).Mul(m.PEv, big.NewInt(3)) },
+		"short_chain":   func(m *ckks.Moduli) { m.RNS = m.RNS[:len(m.RNS)-1] },
+		"repeated":      func(m *ckks.Moduli) { m.RNS[2] = m.RNS[1] },
+		"missing_q0":    func(m *ckks.Moduli) { m.Q0 = nil },
+		"classic_chain": func(m *ckks.Moduli) { m.RNS = nil },
+		"small_P":       func(m *ckks.Moduli) { m.PEv = big.NewInt(3) },
+		"P_in_chain":    func(m *ckks.Moduli) { m.PEv = new(big.Int).SetUint64(m.RNS[1]) },
+		"classic_P":     func(m *ckks.Moduli) { m.PEv = negacyclic.RLWEPrime(120, 2*rnsParams.N) },
+	} {
+		moduli := inst.ParameterSet().Moduli
+		edit(&moduli)
+		if _, err := ckks.NewInstanceWithModuli(rnsParams, &moduli); err == nil {
+			t.Fatalf("%s: expected an error", name)
+		}
+	}
+
+	// A huge depth is rejected before building the chain
+	toy, err := ckks.NewInstance(toyParams)
+	if err != nil && err != ckks.ErrWarningInsecure {
+		t.Fatal(err)
+	}
+	ps := toy.ParameterSet()
+	ps.Parameters.Depth = 1 << 30
+	if _, err := ckks.NewInstanceFromParameterSet(ps); err == nil {
+		t.Fatal("expected an error on a huge depth")
+	}
+}
+
+// Keys of instances with a non-derived P, or a deep chain, should be decodable.
+func testExplicitP(t *testing.T) {
+	toy, err := ckks.NewInstance(toyParams)
+	if err != nil && err != ckks.ErrWarningInsecure {
+		t.Fatal(err)
+	}
+	ps := toy.ParameterSet()
+	ps.Moduli.PEv = negacyclic.RLWEPrime(400, 2*toyParams.N)
+	if _, err := ckks.NewInstanceFromParameterSet(ps); err == nil {
+		t.Fatal("expected an error on a P too large for its keys to be decoded")
+	}
+	ps.Moduli.PEv = negacyclic.RLWEPrime(300, 2*toyParams.N)
+	explicit, err := ckks.NewInstanceFromParameterSet(ps)
+	if err != nil && err != ckks.ErrWarningInsecure {
+		t.Fatal(err)
+	}
+
+	deepParams := *toyParams
+	deepParams.Depth = 12
+	deep, err := ckks.NewInstance(&deepParams)
+	if err != nil && err != ckks.ErrWarningInsecure {
+		t.Fatal(err)
+	}
+
+	for _, inst := range []*ckks.Instance{explicit, deep} {
+		key := inst.GenerateKey()
+		data, err := key.MarshalBinary()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if err := new(ckks.Key).UnmarshalBinary(data); err != nil {
+			t.Fatal(err)
+		}
+		armored, err := inst.EncodeArmored(key)
+		if err != nil {
+			t.Fatal(err)
+		}
+		decoded := new(ckks.Key)
+		if err := inst.DecodeArmored(armored, decoded); err != nil {
+			t.Fatal(err)
+		}
+		if err := inst.Check(decoded); err != nil {
+			t.Fatal(err)
+		}
+	}
+}
@@ -1,6 +1,8 @@
 package ckks
 
 import (
+	"fmt"
+	"math"
 	"math/big"
 
 	"ckks/negacyclic"
@@ -9,15 +11,22 @@ import (
 // Key represents a key object, generated by an instance (see GenerateKey). It
 // is the user's responsibility to handle Key.Secret securely.
 type Key struct {
-	Public     *PublicKey
-	Secret     *SecretKey
-	Evaluation *EvaluationKey
+	Public      *PublicKey
+	Secret      *SecretKey
+	Evaluation  *EvaluationKey
+	Conjugation *EvaluationKey // switches from s(X^-1) to s (see Conjugate)
 }
 
 // PublicKey contains two polynomials. It is used for encryption of plaintext
-// objects (see message.go).
+// objects (see message.go). Its polynomials are kept in evaluation form with
+// respect to the instance ZMultiplier, so that encryption only transforms the
+// fresh randomness. Its uniform polynomial a is expanded from a seed, so that
+// it can be transmitted as the seed alone (see Seed).
 type PublicKey struct {
-	b, a *negacyclic.Polynomial
+	b, a    *negacyclic.RNSPolynomial
+	seed    []byte
+	modulus *big.Int                // q_L
+	zm      *negacyclic.ZMultiplier // of the evaluation form
 }
 
 // SecretKey contains one polynomial with coefficients in {0, 1, -1}. It is
@@ -26,9 +35,42 @@ type SecretKey struct {
 	s *negacyclic.Vector
 }
 
-// EvaluationKey is needed to homomorphically multiply two ciphertexts.
+// EvaluationKey is needed to homomorphically multiply two ciphertexts. Its
+// polynomials are kept in evaluation form as the ones of PublicKey, so that
+// relinearization only transforms the ciphertext. As for PublicKey, its
+// polynomial a' is expanded from a seed. In RNS mode, it is made of a key
+// (b'_i, a'_i) for each prime q_i of the chain (see rns.go), and b, a and zm
+// are nil.
 type EvaluationKey struct {
-	b, a *negacyclic.Polynomial
+	b, a    *negacyclic.RNSPolynomial
+	seed    []byte
+	modulus *big.Int                // P * q_L
+	zm      *negacyclic.ZMultiplier // of the evaluation form
+
+	digitB, digitA []*negacyclic.RNSPolynomial // RNS mode, in evaluation form
+	moduli         []uint64                    // q_0, ..., q_L, P, RNS mode
+}
+
+// Seed returns the seed the polynomial a of this public key is expanded from,
+// as a uniform polynomial modulo q_L (see negacyclic.UniformModFromSeed).
+func (pk *PublicKey) Seed() []byte {
+	return append([]byte(nil), pk.seed...)
+}
+
+// Seed returns the seed the polynomial a' of this key is expanded from, as a
+// uniform polynomial modulo P * q_L (see negacyclic.UniformModFromSeed). In
+// RNS mode, the polynomials a'_i are expanded from it, in evaluation form (see
+// negacyclic.UniformRNSFromSeed).
+func (evk *EvaluationKey) Seed() []byte {
+	return append([]byte(nil), evk.seed...)
+}
+
+// dimension returns the dimension N of the key.
+func (evk *EvaluationKey) dimension() int {
+	if evk.zm == nil {
+		return len(evk.digitB[0].Coeffs[0])
+	}
+	return evk.zm.N
 }
 
 // GenerateKey samples from the correct distributions and returns a Key object.
@@ -46,7 +88,8 @@ func (ins *Instance) GenerateKey() *Key {
 
 	// Sample public key
 	qL := ins.FirstModulus()
-	a := negacyclic.PolynomialFromSlice(negacyclic.UniformMod(dim, qL))
+	seed := negacyclic.NewSeed()
+	a := negacyclic.PolynomialFromSlice(negacyclic.UniformModFromSeed(dim, qL, seed))
 
 	e := negacyclic.VectorFromSlice(negacyclic.DG(dim, ins.Sigma))
 	b := negacyclic.MulSimple(a, s) // b: -as + e mod q_L
@@ -54,56 +97,164 @@ func (ins *Instance) GenerateKey() *Key {
 	b = negacyclic.Add(b, e)
 	b.Mod(qL)
 	pk := PublicKey{
-		a: a,
-		b: b,
+		a:       ins.zMultiplier.ToNTT(a),
+		b:       ins.zMultiplier.ToNTT(b),
+		seed:    seed,
+		modulus: qL,
+		zm:      ins.zMultiplier,
 	}
 
 	// Sample evaluation key
+	evk := ins.switchingKey(s, negacyclic.MulSimple(s, s))
+
+	// Sample conjugation key
+	sConj := negacyclic.Automorphism(s.Polynomial(), negacyclic.ConjugationElement(dim))
+	cjk := ins.switchingKey(s, sConj)
+
+	return &Key{
+		Secret:      &sk,
+		Public:      &pk,
+		Evaluation:  evk,
+		Conjugation: cjk,
+	}
+}
+
+// switchingKey returns a key switching from `target` to the secret key s,
+// i.e. `(b', a')` with `b' = -a's + e' + P target mod P * q_L`, in evaluation
+// form. For `target = s^2`, this is the evaluation key. In RNS mode, the key is
+// decomposed by prime (see switchingKeyRNS).
+func (ins *Instance) switchingKey(s *negacyclic.Vector, target *negacyclic.Polynomial) *EvaluationKey {
+	if ins.RNS {
+		return ins.switchingKeyRNS(s, target)
+	}
+	dim := ins.N
 	P := ins.pEv
 	em := new(big.Int)
-	em.Mul(P, qL) // em - evaluation modulus; P * q_L
-	aBis := negacyclic.PolynomialFromSlice(negacyclic.UniformMod(dim, em))
+	em.Mul(P, ins.FirstModulus()) // em - evaluation modulus; P * q_L
+	seed := negacyclic.NewSeed()
+	aBis := negacyclic.PolynomialFromSlice(negacyclic.UniformModFromSeed(dim, em, seed))
 	eBis := negacyclic.VectorFromSlice(negacyclic.DG(dim, ins.Sigma))
 	bBis := negacyclic.MulSimple(aBis, s)
 	bBis.Negate()
 	bBis = negacyclic.Add(bBis, eBis)
-	ps2 := negacyclic.MulSimple(s, s)
-	ps2.Scale(P)
-	bBis = negacyclic.Add(bBis, ps2) // b': -a's + e' + ps^2 mod p * q_L
+	pTarget := target.Copy()
+	pTarget.Scale(P)
+	bBis = negacyclic.Add(bBis, pTarget) // b': -a's + e' + P target mod P * q_L
 	bBis.Mod(em)
-	evk := EvaluationKey{
-		a: aBis,
-		b: bBis,
-	}
-
-	return &Key{
-		Secret:     &sk,
-		Public:     &pk,
-		Evaluation: &evk,
+	return &EvaluationKey{
+		a:       ins.zMultiplier.ToNTT(aBis),
+		b:       ins.zMultiplier.ToNTT(bBis),
+		seed:    seed,
+		modulus: em,
+		zm:      ins.zMultiplier,
 	}
 }
 
+// keyErrorTail is the multiple of the standard deviation of the errors which
+// bounds the residuals of the keys in Check. A Gaussian exceeds 10 standard
+// deviations with probability below 2^-75.
+const keyErrorTail = 10
+
 // Check verifies if the given key is consistent. A secret key `s` and a public
 // key `(b,a)` match if and only if `b + as mod qL` is a vector with small
 // coefficients. The evaluation key `(b', a')` matches if and only if `(b' + a's
-// - Ps^2) mod P.qL` is a vector with small coefficients.
+// - Ps^2) mod P.qL` is a vector with small coefficients, and likewise for the
+// conjugation key and `s(X^-1)`, and for each key of a prime in RNS mode. The coefficients are small if they are
+// bounded by keyErrorTail standard deviations of the errors, in the symmetric
+// representative. The secret key must be ternary, with the Hamming weight of
+// the instance, and the parts of the key must have the dimension and the
+// moduli of the instance. The public, evaluation and conjugation keys are
+// only checked if present. The returned errors wrap ErrInconsistentKey.
 func (ins *Instance) Check(key *Key) error {
+	if key.Secret == nil {
+		return fmt.Errorf("%w: missing secret key", ErrInconsistentKey)
+	}
+	s := key.Secret.s
+	if len(s.Coeffs) != ins.N {
+		return fmt.Errorf("%w: secret key of dimension %d, want %d", ErrInconsistentKey, len(s.Coeffs), ins.N)
+	}
+	for i, c := range s.Coeffs {
+		if c < -1 || c > 1 {
+			return fmt.Errorf("%w: secret key coefficient %d at index %d is not ternary", ErrInconsistentKey, c, i)
+		}
+	}
+	if h := negacyclic.HammingWeight(s.Coeffs); h != ins.Hamming {
+		return fmt.Errorf("%w: secret key of Hamming weight %d, want %d", ErrInconsistentKey, h, ins.Hamming)
+	}
+
 	// pk v.s sk
-	modulus := ins.FirstModulus()
-	small := negacyclic.MulSimple(key.Public.a, key.Secret.s)
-	small = negacyclic.Add(small, key.Public.b)
-	small.Mod(modulus)
+	if pk := key.Public; pk != nil {
+		if err := ins.checkKeyModulus("public", pk.modulus, pk.zm, ins.FirstModulus()); err != nil {
+			return err
+		}
+		small := negacyclic.MulSimple(pk.zm.FromNTT(pk.a), s)
+		small = negacyclic.Add(small, pk.zm.FromNTT(pk.b))
+		if err := ins.checkResidual("public", small.Mod(pk.modulus)); err != nil {
+			return err
+		}
+	}
 	// evk v.s sk
-	modulus.Mul(modulus, ins.pEv)
-	small = negacyclic.MulSimple(key.Evaluation.a, key.Secret.s)
-	small = negacyclic.Add(small, key.Evaluation.b)
-	small.Mod(modulus)
-	ps2 := negacyclic.MulSimple(key.Secret.s, key.Secret.s)
-	for _, coeff := range ps2.Coeffs {
-		coeff.Mul(coeff, ins.pEv)
-		coeff.Neg(coeff)
-	}
-	small = negacyclic.Add(small, ps2)
-	small.Mod(modulus)
+	if evk := key.Evaluation; evk != nil {
+		if err := ins.checkSwitchingKey("evaluation", evk, s, negacyclic.MulSimple(s, s)); err != nil {
+			return err
+		}
+	}
+	if cjk := key.Conjugation; cjk != nil {
+		sConj := negacyclic.Automorphism(s.Polynomial(), negacyclic.ConjugationElement(ins.N))
+		if err := ins.checkSwitchingKey("conjugation", cjk, s, sConj); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// checkSwitchingKey checks that `b' + a's - P target mod P.qL` is small, for
+// a key (b', a') switching from `target` to s. In RNS mode, the keys of the
+// primes are checked with checkDigitKeys.
+func (ins *Instance) checkSwitchingKey(name string, evk *EvaluationKey, s *negacyclic.Vector, target *negacyclic.Polynomial) error {
+	if ins.RNS {
+		return ins.checkDigitKeys(name, evk, s, target)
+	}
+	if evk.zm == nil {
+		return fmt.Errorf("%w: %s key decomposed by prime, for a classic chain", ErrInconsistentKey, name)
+	}
+	modulus := new(big.Int).Mul(ins.FirstModulus(), ins.pEv)
+	if err := ins.checkKeyModulus(name, evk.modulus, evk.zm, modulus); err != nil {
+		return err
+	}
+	small := negacyclic.MulSimple(evk.zm.FromNTT(evk.a), s)
+	small = negacyclic.Add(small, evk.zm.FromNTT(evk.b))
+	pTarget := target.Copy()
+	pTarget.Scale(ins.pEv)
+	pTarget.Negate()
+	small = negacyclic.Add(small, pTarget)
+	return ins.checkResidual(name, small.Mod(modulus))
+}
+
+// checkKeyModulus checks that a key modulo `modulus`, in evaluation form with
+// respect to zm, has the dimension of the instance and is modulo `want`.
+func (ins *Instance) checkKeyModulus(name string, modulus *big.Int, zm *negacyclic.ZMultiplier, want *big.Int) error {
+	if zm.N != ins.N {
+		return fmt.Errorf("%w: %s key of dimension %d, want %d", ErrInconsistentKey, name, zm.N, ins.N)
+	}
+	if modulus.Cmp(want) != 0 {
+		return fmt.Errorf("%w: %s key modulo a modulus of %d bits, want %d bits", ErrInconsistentKey,
+			name, modulus.BitLen(), want.BitLen())
+	}
+	return nil
+}
+
+// checkResidual checks that the coefficients of the residual of a key, in
+// the symmetric representative, are bounded by keyErrorTail standard
+// deviations of the errors.
+func (ins *Instance) checkResidual(name string, residual *negacyclic.Polynomial) error {
+	bound := big.NewInt(int64(math.Ceil(keyErrorTail * errorStdDev(ins.Sigma))))
+	abs := new(big.Int)
+	for i, c := range residual.Coeffs {
+		if abs.Abs(c).Cmp(bound) > 0 {
+			return fmt.Errorf("%w: %s key residual has coefficient of %d bits at index %d, bound %s",
+				ErrInconsistentKey, name, c.BitLen(), i, bound)
+		}
+	}
 	return nil
 }
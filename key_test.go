@@ -1,6 +1,7 @@
 package ckks_test
 
 import (
+	"errors"
 	"testing"
 
 	"ckks"
@@ -8,6 +9,7 @@ import (
 
 func testKeyGeneration(ins *ckks.Instance, t *testing.T) {
 	t.Run("good_keygen", func(t *testing.T) { kgGood(ins, t) })
+	t.Run("check", func(t *testing.T) { testCheck(ins, t) })
 }
 
 func kgGood(inst *ckks.Instance, t *testing.T) {
@@ -21,6 +23,47 @@ func kgGood(inst *ckks.Instance, t *testing.T) {
 	}
 }
 
+func testCheck(inst *ckks.Instance, t *testing.T) {
+	key := precompEncDec.key
+	if err := inst.Check(key); err != nil {
+		t.Fatal(err)
+	}
+	other := inst.GenerateKey()
+	for name, bad := range map[string]*ckks.Key{
+		"public":      {Secret: key.Secret, Public: other.Public},
+		"evaluation":  {Secret: key.Secret, Evaluation: other.Evaluation},
+		"conjugation": {Secret: key.Secret, Conjugation: other.Conjugation},
+		"secret":      {Secret: other.Secret, Public: key.Public, Evaluation: key.Evaluation},
+		"missing":     {Public: key.Public},
+	} {
+		if err := inst.Check(bad); !errors.Is(err, ckks.ErrInconsistentKey) {
+			t.Fatalf("%s: expected ErrInconsistentKey, got %v", name, err)
+		}
+	}
+
+	// The secret key of another dimension.
+	for _, ti := range testInstances {
+		if ti.ins.N != inst.N {
+			bad := &ckks.Key{Secret: ti.ins.GenerateKey().Secret}
+			if err := inst.Check(bad); !errors.Is(err, ckks.ErrInconsistentKey) {
+				t.Fatalf("expected ErrInconsistentKey, got %v", err)
+			}
+			break
+		}
+	}
+
+	// The evaluation key of the other mode, decomposed by prime or not.
+	for _, ti := range testInstances {
+		if ti.ins.RNS != inst.RNS {
+			bad := &ckks.Key{Secret: key.Secret, Evaluation: ti.ins.GenerateKey().Evaluation}
+			if err := inst.Check(bad); !errors.Is(err, ckks.ErrInconsistentKey) {
+				t.Fatalf("expected ErrInconsistentKey, got %v", err)
+			}
+			break
+		}
+	}
+}
+
 func benchKeyGen(b *testing.B) {
 	for i := 0; i < b.N; i++ {
 		instBench.GenerateKey()
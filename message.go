@@ -7,48 +7,120 @@ import (
 	"ckks/negacyclic"
 )
 
-// Plaintext is a native plaintext of the scheme, post encoding.
+// ScalePrecision is the precision, in bits, of the scales of plaintexts and
+// ciphertexts.
+const ScalePrecision = 256
+
+// ScaleTolerance is the largest relative difference between the scales of two
+// ciphertexts that can be added (see Instance.Add).
+const ScaleTolerance = 1e-3
+
+// Plaintext is a native plaintext of the scheme, post encoding. Its scale is
+// the factor applied to the message on encoding (see Instance.Encode).
 type Plaintext struct {
-	m *negacyclic.Polynomial
+	m     *negacyclic.Polynomial
+	scale *big.Float
+	nu    *big.Float // bound of the message, if known on encoding (see bound)
 }
 
 // Ciphertext contains all the tagged informations for noise management, and
-// the encrypted data.
+// the encrypted data. Its components c_0, ..., c_d decrypt with the powers of
+// the secret key, as c_0 + c_1 s + ... + c_d s^d, where d is the degree of the
+// ciphertext: (c_0, c_1) = (b, a) for fresh ciphertexts, and products have
+// degree 2 until relinearized (see Instance.MulNoRelin).
+//
+// The polynomials are either in coefficient form, or in evaluation form with
+// respect to the instance multiplier of its level (see Instance.ToNTT). In RNS
+// mode, the polynomials are stored as their residues modulo the primes q_0,
+// ..., q_l instead, which the ciphertext remembers.
+//
+// The scale of a ciphertext is the one of the plaintext it decrypts to. It is
+// updated by the homomorphic operations (see homomorphic.go), together with the
+// bounds ν of the message and B of the noise (see noise.go).
+//
+// Fresh secret-key ciphertexts remember the seed their component a is expanded
+// from (see Instance.EncryptSk and Seed). Ciphertexts resulting from
+// homomorphic operations have no seed.
 type Ciphertext struct {
-	a, b  *negacyclic.Polynomial
-	level int
-	ql    *big.Int
+	c      []*negacyclic.Polynomial
+	rns    []*negacyclic.RNSPolynomial
+	moduli []uint64 // q_0, ..., q_l, in RNS mode
+	level  int
+	ql     *big.Int
+	scale  *big.Float
+	nu     *big.Float // bound of the message (see MessageBound)
+	noise  *big.Float // bound of the noise (see NoiseBound)
+	seed   []byte
 }
 
-// String is the stringer method of a ciphertext
+// String is the stringer method of a ciphertext. It describes the ciphertext
+// for debugging, and is not an encoding (see EncodeArmored).
 func (ciph *Ciphertext) String() string {
-	str := "----- BEGIN CIPHERTEXT -----\n"
-	str += "level:    " + strconv.Itoa(ciph.level) + "\n"
-	str += "modulus:  " + ciph.ql.String() + "\n"
-	str += "a[0]:     " + ciph.a.Coeffs[0].String() + "\n"
-	str += "b[0]:     " + ciph.b.Coeffs[0].String() + "\n"
-	str += "----- END CIPHERTEXT -----\n"
+	str := "Ciphertext:\n"
+	str += "  level:    " + strconv.Itoa(ciph.level) + "\n"
+	str += "  degree:   " + strconv.Itoa(ciph.Degree()) + "\n"
+	str += "  modulus:  " + ciph.ql.String() + "\n"
+	str += "  scale:    " + ciph.scale.Text('g', 10) + "\n"
+	str += "  noise:    " + ciph.noise.Text('g', 10) + "\n"
+	for k := 0; k <= ciph.Degree(); k++ {
+		str += "  c" + strconv.Itoa(k) + "[0]:    "
+		if ciph.rns != nil {
+			str += residuesString(ciph.rns[k]) + "\n"
+		} else {
+			str += ciph.c[k].Coeffs[0].String() + "\n"
+		}
+	}
 	return str
 }
 
 // Clone returns a copy of the receiver ciphertext
 func (ciph *Ciphertext) Clone() *Ciphertext {
-	a := negacyclic.PolynomialFromSlice(ciph.a.Coeffs)
-	b := negacyclic.PolynomialFromSlice(ciph.b.Coeffs)
-	ql := new(big.Int).Set(ciph.ql)
-	level := ciph.level
-	return &Ciphertext{
-		a:     a,
-		b:     b,
-		level: level,
-		ql:    ql,
+	clone := &Ciphertext{
+		moduli: ciph.moduli,
+		level:  ciph.level,
+		ql:     new(big.Int).Set(ciph.ql),
+		scale:  new(big.Float).Copy(ciph.scale),
+		nu:     new(big.Float).Copy(ciph.nu),
+		noise:  new(big.Float).Copy(ciph.noise),
+		seed:   ciph.Seed(),
+	}
+	if ciph.rns != nil {
+		clone.rns = make([]*negacyclic.RNSPolynomial, len(ciph.rns))
+		for k := range ciph.rns {
+			clone.rns[k] = ciph.rns[k].Copy()
+		}
+	} else {
+		clone.c = make([]*negacyclic.Polynomial, len(ciph.c))
+		for k := range ciph.c {
+			clone.c[k] = ciph.c[k].Copy()
+		}
+	}
+	return clone
+}
+
+// Degree returns the degree of this ciphertext, i.e. the largest power of the
+// secret key it decrypts with.
+func (ciph *Ciphertext) Degree() int {
+	if ciph.rns != nil {
+		return len(ciph.rns) - 1
 	}
+	return len(ciph.c) - 1
+}
+
+// Seed returns the seed the component a of this ciphertext is expanded from,
+// as a uniform polynomial modulo q_L (see negacyclic.UniformModFromSeed), or
+// nil if the ciphertext is not a fresh secret-key encryption.
+func (ciph *Ciphertext) Seed() []byte {
+	if ciph.seed == nil {
+		return nil
+	}
+	return append([]byte(nil), ciph.seed...)
 }
 
 // NewPlaintextFromNegacyclic returns a plaintext with the given underlying
-// polynomial.
+// polynomial, and scale 1 (see SetScale).
 func NewPlaintextFromNegacyclic(pol *negacyclic.Polynomial) *Plaintext {
-	return &Plaintext{m: pol}
+	return &Plaintext{m: pol, scale: newScale(big.NewInt(1))}
 }
 
 // GetPolynomial returns the underlying polynomial of this plaintext.
@@ -65,3 +137,64 @@ func (ciph *Ciphertext) Level() int {
 func (ciph *Ciphertext) Modulus() *big.Int {
 	return new(big.Int).Set(ciph.ql)
 }
+
+// Scale returns the scale of this plaintext.
+func (plt *Plaintext) Scale() *big.Float {
+	return new(big.Float).Copy(plt.scale)
+}
+
+// SetScale sets the scale of this plaintext.
+func (plt *Plaintext) SetScale(scale *big.Float) {
+	plt.scale = new(big.Float).SetPrec(ScalePrecision).Set(scale)
+}
+
+// Scale returns the scale of this ciphertext.
+func (ciph *Ciphertext) Scale() *big.Float {
+	return new(big.Float).Copy(ciph.scale)
+}
+
+// SetScale sets the scale of this ciphertext, e.g. to consider a rescaled
+// ciphertext at the scale of fresh ones.
+func (ciph *Ciphertext) SetScale(scale *big.Float) {
+	ciph.scale = new(big.Float).SetPrec(ScalePrecision).Set(scale)
+}
+
+// withComponents returns a ciphertext with the level, modulus, scale and
+// bounds of ciph, and the given components.
+func (ciph *Ciphertext) withComponents(c []*negacyclic.Polynomial) *Ciphertext {
+	return &Ciphertext{
+		c:     c,
+		level: ciph.level,
+		ql:    new(big.Int).Set(ciph.ql),
+		scale: new(big.Float).Copy(ciph.scale),
+		nu:    new(big.Float).Copy(ciph.nu),
+		noise: new(big.Float).Copy(ciph.noise),
+	}
+}
+
+// withResidues is the RNS counterpart of withComponents.
+func (ciph *Ciphertext) withResidues(rns []*negacyclic.RNSPolynomial) *Ciphertext {
+	return &Ciphertext{
+		rns:    rns,
+		moduli: ciph.moduli,
+		level:  ciph.level,
+		ql:     new(big.Int).Set(ciph.ql),
+		scale:  new(big.Float).Copy(ciph.scale),
+		nu:     new(big.Float).Copy(ciph.nu),
+		noise:  new(big.Float).Copy(ciph.noise),
+	}
+}
+
+func newScale(x *big.Int) *big.Float {
+	return new(big.Float).SetPrec(ScalePrecision).SetInt(x)
+}
+
+// scalesMatch returns true iff the relative difference of x and y is at most
+// ScaleTolerance.
+func scalesMatch(x, y *big.Float) bool {
+	diff := new(big.Float).SetPrec(ScalePrecision).Sub(x, y)
+	diff.Abs(diff)
+	bound := new(big.Float).SetPrec(ScalePrecision).Abs(x)
+	bound.Mul(bound, big.NewFloat(ScaleTolerance))
+	return diff.Cmp(bound) <= 0
+}
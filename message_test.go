@@ -1,6 +1,7 @@
 package ckks_test
 
 import (
+	"bytes"
 	"math/big"
 	"math/rand"
 	"testing"
@@ -38,6 +39,85 @@ func testEncryption(ins *ckks.Instance, t *testing.T) {
 	println("OK")
 	t.Run("key_generation", func(t *testing.T) { testKeyGeneration(ins, t) })
 	t.Run("encrypt_decrypt_roundtrip", func(t *testing.T) { testEncDec(ins, t) })
+	t.Run("encrypt_decrypt_fractional", func(t *testing.T) { testEncDecFractional(ins, t) })
+	t.Run("secret_key_encryption", func(t *testing.T) { testEncryptSk(ins, t) })
+	t.Run("seeds", func(t *testing.T) { testSeeds(ins, t) })
+}
+
+func testEncryptSk(inst *ckks.Instance, t *testing.T) {
+	key := precompEncDec.key
+	plt := precompEncDec.pltxs[0]
+	msg := precompEncDec.msgs[0]
+	ciphertext := inst.EncryptSk(key.Secret, plt)
+	if ciphertext.Level() != inst.Depth || ciphertext.Scale().Cmp(plt.Scale()) != 0 {
+		t.Fatal("fresh ciphertexts should be at the top level, at the plaintext scale")
+	}
+	decrypted := inst.Decrypt(key.Secret, ciphertext)
+	checkResult(inst.Decode(decrypted), msg, t)
+
+	// The noise is a single Gaussian polynomial, within BcleanSk
+	noise := negacyclic.Sub(decrypted.GetPolynomial(), plt.GetPolynomial())
+	for _, coeff := range noise.Coeffs {
+		if coeff.CmpAbs(inst.BcleanSk()) > 0 {
+			t.Fatalf("noise coefficient %s exceeds %s", coeff, inst.BcleanSk())
+		}
+	}
+	if inst.BcleanSk().Cmp(inst.Bclean()) >= 0 {
+		t.Fatal("secret key encryption should have a smaller noise bound")
+	}
+}
+
+func testSeeds(inst *ckks.Instance, t *testing.T) {
+	key := precompEncDec.key
+	seeds := [][]byte{key.Public.Seed(), key.Evaluation.Seed(), key.Conjugation.Seed()}
+	for i, seed := range seeds {
+		if len(seed) != negacyclic.SeedSize {
+			t.Fatalf("key %d has a seed of length %d", i, len(seed))
+		}
+		for j := 0; j < i; j++ {
+			if bytes.Equal(seed, seeds[j]) {
+				t.Fatal("keys should be expanded from distinct seeds")
+			}
+		}
+	}
+
+	plt := precompEncDec.pltxs[0]
+	ciphertext := inst.EncryptSk(key.Secret, plt)
+	if len(ciphertext.Seed()) != negacyclic.SeedSize {
+		t.Fatal("secret key ciphertexts should remember their seed")
+	}
+	if !bytes.Equal(ciphertext.Clone().Seed(), ciphertext.Seed()) {
+		t.Fatal("clones should keep the seed")
+	}
+	if inst.Encrypt(key.Public, plt).Seed() != nil {
+		t.Fatal("public key ciphertexts have no seed")
+	}
+	sum, err := inst.Add(ciphertext, ciphertext)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if sum.Seed() != nil {
+		t.Fatal("homomorphic operations should drop the seed")
+	}
+	inst.RS(ciphertext, inst.Depth-1)
+	if ciphertext.Seed() != nil {
+		t.Fatal("rescaling should drop the seed")
+	}
+}
+
+func testEncDecFractional(inst *ckks.Instance, t *testing.T) {
+	key := precompEncDec.key
+	delta := big.NewInt(1 << 40)
+	msg := make([]complex128, inst.N/2)
+	for i := range msg {
+		msg[i] = complex(rand.Float64(), rand.Float64())
+	}
+	plt, err := inst.Encode(msg, delta)
+	if err != nil {
+		t.Fatal(err)
+	}
+	decrypted := inst.Decrypt(key.Secret, inst.Encrypt(key.Public, plt))
+	checkCloseWithin(inst.DecodeComplex(decrypted), msg, 1e-4, t)
 }
 
 func testEncDec(inst *ckks.Instance, t *testing.T) {
@@ -47,7 +127,7 @@ func testEncDec(inst *ckks.Instance, t *testing.T) {
 
 	// Decrypt the plaintext
 	decrypted := inst.Decrypt(key.Secret, ciphertext)
-	decoded := inst.Decode(decrypted, precompEncDec.delta)
+	decoded := inst.Decode(decrypted)
 	for i := 0; i < len(msg); i++ {
 		if msg[i] != decoded[i] {
 			t.Errorf("Bad decryption; want %f got %f", msg[i], decoded[i])
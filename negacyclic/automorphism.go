@@ -0,0 +1,86 @@
+package negacyclic
+
+// GaloisElement returns 5^k mod 2n, the exponent of the automorphism
+// X -> X^(5^k) of Z[X]/(X^n+1). The powers of 5 have order n/2 modulo 2n, so
+// that k is read modulo n/2, and may be negative.
+func GaloisElement(k, n int) int {
+	if !isPowerOfTwo(n) || n < 4 {
+		panic("galois element expects `n` power of two, at least 4")
+	}
+	k %= n / 2
+	if k < 0 {
+		k += n / 2
+	}
+	g := 1
+	for i := 0; i < k; i++ {
+		g = g * 5 % (2 * n)
+	}
+	return g
+}
+
+// ConjugationElement returns 2n - 1, the exponent of the automorphism
+// X -> X^(-1) of Z[X]/(X^n+1). It conjugates the evaluations of polynomials
+// at the primitive 2n-th roots of unity.
+func ConjugationElement(n int) int {
+	if !isPowerOfTwo(n) {
+		panic("conjugation element expects `n` power of two")
+	}
+	return 2*n - 1
+}
+
+// Automorphism returns p(X^g), for an odd integer g, possibly negative. As
+// X^(2n) = 1, the monomial X^i is mapped to ±X^(ig mod n), with a negative
+// sign iff ig mod 2n >= n. The input must be in coefficient form.
+func Automorphism(p *Polynomial, g int) *Polynomial {
+	if p.isNTT {
+		panic("cannot apply an automorphism to a polynomial in NTT form")
+	}
+	n := p.Deg()
+	res := NewPolynomial(n)
+	for i, coeff := range p.Coeffs {
+		j, neg := automorphismIndex(i, g, n)
+		res.Coeffs[j].Set(coeff)
+		if neg {
+			res.Coeffs[j].Neg(res.Coeffs[j])
+		}
+	}
+	return res
+}
+
+// Automorphism returns x(X^g), for an odd integer g (see Automorphism). The
+// input must be in coefficient form.
+func (r *RNSRing) Automorphism(x *RNSPolynomial, g int) *RNSPolynomial {
+	r.checkLimbs(x)
+	if x.isNTT {
+		panic("cannot apply an automorphism to a polynomial in NTT form")
+	}
+	z := r.NewPolynomial()
+	for i := 0; i < r.N; i++ {
+		j, neg := automorphismIndex(i, g, r.N)
+		for l, q := range r.Moduli {
+			if neg {
+				z.Coeffs[l][j] = subMod(0, x.Coeffs[l][i], q)
+			} else {
+				z.Coeffs[l][j] = x.Coeffs[l][i]
+			}
+		}
+	}
+	return z
+}
+
+// automorphismIndex returns the index j and the sign of the monomial X^j such
+// that X^(ig) = ±X^j in Z[X]/(X^n+1). The exponent g may be negative.
+func automorphismIndex(i, g, n int) (int, bool) {
+	if g%2 == 0 {
+		panic("automorphism expects an odd exponent")
+	}
+	g %= 2 * n
+	if g < 0 {
+		g += 2 * n
+	}
+	j := i * g % (2 * n)
+	if j >= n {
+		return j - n, true
+	}
+	return j, false
+}
@@ -0,0 +1,91 @@
+package negacyclic_test
+
+import (
+	"math/big"
+	"testing"
+
+	"ckks/negacyclic"
+)
+
+func TestAutomorphism(t *testing.T) {
+	t.Run("galois_elements", testGaloisElements)
+	t.Run("ring_homomorphism", testAutomorphismHomomorphism)
+	t.Run("composition", testAutomorphismComposition)
+	t.Run("RNS_matches_polynomial", testRNSAutomorphism)
+	t.Run("conjugation_involution", testConjugationInvolution)
+}
+
+func testGaloisElements(t *testing.T) {
+	n := 1 << 6
+	seen := make(map[int]bool)
+	for k := 0; k < n/2; k++ {
+		g := negacyclic.GaloisElement(k, n)
+		if g%4 != 1 || seen[g] {
+			t.Fatalf("5^%d mod 2n = %d is not a new element of the orbit", k, g)
+		}
+		seen[g] = true
+	}
+	if negacyclic.GaloisElement(n/2, n) != 1 {
+		t.Fatal("5 should have order n/2 modulo 2n")
+	}
+	if negacyclic.GaloisElement(-1, n) != negacyclic.GaloisElement(n/2-1, n) {
+		t.Fatal("negative steps should be read modulo n/2")
+	}
+}
+
+func testAutomorphismHomomorphism(t *testing.T) {
+	n := 1 << 6
+	bound := big.NewInt(1 << 20)
+	x := randomSignedElement(n, bound)
+	y := randomSignedElement(n, bound)
+	for _, g := range []int{5, 25, -1, 2*n - 1} {
+		got := negacyclic.Automorphism(negacyclic.Karatsuba(x, y), g)
+		checkExactProduct(t, got, negacyclic.Automorphism(x, g), negacyclic.Automorphism(y, g))
+	}
+}
+
+func testAutomorphismComposition(t *testing.T) {
+	n := 1 << 6
+	x := randomSignedElement(n, big.NewInt(1<<20))
+	got := negacyclic.Automorphism(negacyclic.Automorphism(x, 5), 5)
+	want := negacyclic.Automorphism(x, 25)
+	for i := range want.Coeffs {
+		if got.Coeffs[i].Cmp(want.Coeffs[i]) != 0 {
+			t.Fatalf("got %s, want %s", got.Coeffs[i], want.Coeffs[i])
+		}
+	}
+}
+
+func testRNSAutomorphism(t *testing.T) {
+	n := 1 << 8
+	r := negacyclic.NewRNSRing(n, negacyclic.RNSPrimes(60, 2*n, 3))
+	x := randomElement(n, r.Q)
+	x.Mod(r.Q)
+	g := negacyclic.GaloisElement(3, n)
+	got := r.ToPolynomial(r.Automorphism(r.FromPolynomial(x), g))
+	want := negacyclic.Automorphism(x, g).Mod(r.Q)
+	for i := range want.Coeffs {
+		if got.Coeffs[i].Cmp(want.Coeffs[i]) != 0 {
+			t.Fatalf("got %s, want %s", got.Coeffs[i], want.Coeffs[i])
+		}
+	}
+}
+
+func testConjugationInvolution(t *testing.T) {
+	n := 1 << 6
+	x := randomSignedElement(n, big.NewInt(1<<20))
+	g := negacyclic.ConjugationElement(n)
+	got := negacyclic.Automorphism(negacyclic.Automorphism(x, g), g)
+	for i := range x.Coeffs {
+		if got.Coeffs[i].Cmp(x.Coeffs[i]) != 0 {
+			t.Fatalf("got %s, want %s", got.Coeffs[i], x.Coeffs[i])
+		}
+	}
+	// X^(-1) = -X^(n-1)
+	monomial := negacyclic.NewPolynomial(n)
+	monomial.Coeffs[1].SetInt64(1)
+	conj := negacyclic.Automorphism(monomial, g)
+	if conj.Coeffs[n-1].Cmp(big.NewInt(-1)) != 0 {
+		t.Fatalf("got %s, want -X^(n-1)", conj)
+	}
+}
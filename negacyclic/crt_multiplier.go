@@ -5,47 +5,144 @@ import (
 )
 
 // CRTMultiplier handles the multiplication in a negacyclic ring of the form
-// Z_{pq}[X]/(X^n+1), where p and q are primes. Internally, it operates modulo
-// p and modulo q with NTT, and uses CRT.
+// Z_Q[X]/(X^n+1), where Q = p_0 * ... * p_{k-1} is a product of distinct
+// primes. Internally, it operates modulo each p_i with NTT, and reconstructs
+// the result with Garner's algorithm.
+//
+// The evaluation form of a polynomial (see ToNTT) is the CRT combination of its
+// evaluation forms modulo each p_i. Hence, the evaluation form modulo the first
+// primes (see Prefix) is obtained by reducing modulo their product.
 type CRTMultiplier struct {
-	PQ          *big.Int
+	PQ          *big.Int // Product of all the primes
 	N           int
-	pInvQ       *big.Int
-	multiplierP *Multiplier
-	multiplierQ *Multiplier
+	Primes      []*big.Int
+	multipliers []*Multiplier
+
+	// Garner: prods[i] = p_0 * ... * p_{i-1}, prodInvs[i] = prods[i]^{-1} mod p_i.
+	prods    []*big.Int
+	prodInvs []*big.Int
 }
 
 // NewCRTMultiplier creates and returns a CRTMultiplier with the given
 // parameters, after proper sanitization.
-func NewCRTMultiplier(n int, p, q *big.Int) *CRTMultiplier {
+func NewCRTMultiplier(n int, primes ...*big.Int) *CRTMultiplier {
 	if !isPowerOfTwo(n) {
 		panic("multiplier expects `n` power of two")
 	}
-	if !p.ProbablyPrime(32) || !q.ProbablyPrime(32) || p.Cmp(q) == 0 {
-		panic("multiplier expects two coprime moduli")
+	if len(primes) < 2 {
+		panic("multiplier expects at least two moduli")
 	}
 	m := new(CRTMultiplier)
 	m.N = n
-	m.PQ = new(big.Int).Mul(p, q)
-	m.pInvQ = modularInverse(p, q)
-	m.multiplierP = NewMultiplier(n, p)
-	m.multiplierQ = NewMultiplier(n, q)
+	m.Primes = make([]*big.Int, len(primes))
+	m.multipliers = make([]*Multiplier, len(primes))
+	m.prods = make([]*big.Int, len(primes)+1)
+	m.prodInvs = make([]*big.Int, len(primes))
+	m.prods[0] = big.NewInt(1)
+	for i, p := range primes {
+		if !p.ProbablyPrime(32) {
+			panic("multiplier expects prime moduli")
+		}
+		for _, prev := range primes[:i] {
+			if p.Cmp(prev) == 0 {
+				panic("multiplier expects coprime moduli")
+			}
+		}
+		m.Primes[i] = new(big.Int).Set(p)
+		m.multipliers[i] = NewMultiplier(n, m.Primes[i])
+		m.prodInvs[i] = modularInverse(new(big.Int).Mod(m.prods[i], p), p)
+		m.prods[i+1] = new(big.Int).Mul(m.prods[i], p)
+	}
+	m.PQ = m.prods[len(primes)]
 	return m
 }
 
+// Prefix returns the multiplier modulo the product of the first `count`
+// primes, sharing the precomputations of m. This drops the last primes, e.g.
+// when the modulus of the operands decreases. As for NewCRTMultiplier, at least
+// two primes must be kept.
+func (m *CRTMultiplier) Prefix(count int) *CRTMultiplier {
+	if count < 2 || count > len(m.Primes) {
+		panic("invalid number of primes")
+	}
+	return &CRTMultiplier{
+		PQ:          m.prods[count],
+		N:           m.N,
+		Primes:      m.Primes[:count],
+		multipliers: m.multipliers[:count],
+		prods:       m.prods[:count+1],
+		prodInvs:    m.prodInvs[:count],
+	}
+}
+
 // Mul computes the product of x and y in the corresponding negacyclic ring.
+// The operands can be in either form, and only the ones in coefficient form
+// are transformed. The result is in coefficient form, with coefficients in
+// [0, Q).
 func (m *CRTMultiplier) Mul(x, y *Polynomial) *Polynomial {
-	a := m.multiplierP.Mul(x, y)
-	b := m.multiplierQ.Mul(x, y)
-
-	// Inverse of the larger modulus, modulo the smaller modulus
-	// Note z = a + p * [p^-1]_q (b - a) satisfies z = a mod p, z = b mod q.
-	p := m.multiplierP.Mod
-	z := NewPolynomial(x.Deg())
-	for i := range x.Coeffs {
-		z.Coeffs[i] = new(big.Int)
-		z.Coeffs[i].Sub(b.Coeffs[i], a.Coeffs[i]).Mul(z.Coeffs[i], m.pInvQ)
-		z.Coeffs[i].Mul(z.Coeffs[i], p).Add(z.Coeffs[i], a.Coeffs[i])
+	residues := make([]*Polynomial, len(m.multipliers))
+	for i, mul := range m.multipliers {
+		residues[i] = mul.Mul(x, y)
+	}
+	return m.crt(residues)
+}
+
+// ToNTT returns the evaluation form of x. It returns x itself if it is already
+// in evaluation form.
+func (m *CRTMultiplier) ToNTT(x *Polynomial) *Polynomial {
+	if x.isNTT {
+		return x
+	}
+	residues := make([]*Polynomial, len(m.multipliers))
+	for i, mul := range m.multipliers {
+		residues[i] = mul.ToNTT(x)
+	}
+	z := m.crt(residues)
+	z.isNTT = true
+	return z
+}
+
+// FromNTT returns the coefficient form of x, with coefficients in [0, Q). It
+// returns x itself if it is already in coefficient form.
+func (m *CRTMultiplier) FromNTT(x *Polynomial) *Polynomial {
+	if !x.isNTT {
+		return x
+	}
+	residues := make([]*Polynomial, len(m.multipliers))
+	for i, mul := range m.multipliers {
+		residues[i] = mul.FromNTT(x)
+	}
+	return m.crt(residues)
+}
+
+// Hadamard returns a polynomial `c` with `c[i] = a[i] * b[i] mod Q`. If a and
+// b are in evaluation form, then c is their product in evaluation form.
+func (m *CRTMultiplier) Hadamard(a, b *Polynomial) *Polynomial {
+	if a.Deg() != b.Deg() {
+		panic("asymmetric multiplication call")
+	}
+	c := NewPolynomial(a.Deg())
+	c.isNTT = true
+	for i := range a.Coeffs {
+		c.Coeffs[i].Mul(a.Coeffs[i], b.Coeffs[i]).Mod(c.Coeffs[i], m.PQ)
+	}
+	return c
+}
+
+// crt returns z in [0, Q) with z = residues[i] mod p_i for each i.
+func (m *CRTMultiplier) crt(residues []*Polynomial) *Polynomial {
+	// Garner: z_{i+1} = z_i + P_i * [P_i^{-1} (r_i - z_i)]_{p_i}, where P_i is
+	// the product of the first i primes, satisfies z_{i+1} = r_j mod p_j for
+	// all j <= i.
+	z := NewPolynomial(residues[0].Deg())
+	aux := new(big.Int)
+	for j, coeff := range z.Coeffs {
+		coeff.Mod(residues[0].Coeffs[j], m.Primes[0])
+		for i := 1; i < len(residues); i++ {
+			aux.Sub(residues[i].Coeffs[j], coeff).Mul(aux, m.prodInvs[i])
+			aux.Mod(aux, m.Primes[i])
+			coeff.Add(coeff, aux.Mul(aux, m.prods[i]))
+		}
 	}
 	return z
 }
@@ -1,6 +1,7 @@
 package negacyclic_test
 
 import (
+	"math/big"
 	"testing"
 
 	"ckks/negacyclic"
@@ -8,6 +9,9 @@ import (
 
 func TestPolynomialCRTMultiplication(t *testing.T) {
 	t.Run("nttCRTMedium", testNTTCRTMedium)
+	t.Run("nttCRTEvaluationForm", testNTTCRTEvaluationForm)
+	t.Run("nttCRTChain", testNTTCRTChain)
+	t.Run("nttCRTPrefix", testNTTCRTPrefix)
 }
 
 func testNTTCRTMedium(t *testing.T) {
@@ -30,3 +34,91 @@ func testNTTCRTMedium(t *testing.T) {
 		}
 	}
 }
+
+func testNTTCRTEvaluationForm(t *testing.T) {
+	n := 1 << 8
+	p := negacyclic.RLWEPrime(40, 2*n)
+	q := negacyclic.RLWEPrime(100, 2*n)
+	m := negacyclic.NewCRTMultiplier(n, p, q)
+	x := randomElement(n, m.PQ)
+	y := randomElement(n, m.PQ)
+	want := m.Mul(x, y)
+
+	xNTT, yNTT := m.ToNTT(x), m.ToNTT(y)
+	if !xNTT.IsNTT() || x.IsNTT() {
+		t.Fatal("ToNTT should return a copy in evaluation form")
+	}
+	roundtrip := m.FromNTT(xNTT)
+	mixed := m.Mul(xNTT, y)
+	hadamard := m.FromNTT(m.Hadamard(xNTT, yNTT))
+	sum := m.FromNTT(negacyclic.Add(xNTT, yNTT)).Mod(m.PQ)
+	wantSum := negacyclic.Add(x, y).Mod(m.PQ)
+	for i := 0; i < n; i++ {
+		if roundtrip.Coeffs[i].Cmp(x.Coeffs[i]) != 0 {
+			t.Fatal("evaluation form roundtrip failed")
+		}
+		if mixed.Coeffs[i].Cmp(want.Coeffs[i]) != 0 {
+			t.Fatal("incorrect product with an operand in evaluation form")
+		}
+		if hadamard.Coeffs[i].Cmp(want.Coeffs[i]) != 0 {
+			t.Fatal("incorrect product in evaluation form")
+		}
+		if sum.Coeffs[i].Cmp(wantSum.Coeffs[i]) != 0 {
+			t.Fatal("incorrect addition in evaluation form")
+		}
+	}
+}
+
+func testNTTCRTChain(t *testing.T) {
+	n := 1 << 8
+	m := negacyclic.NewCRTMultiplier(n, chainOfPrimes(n, 60, 60, 100, 45)...)
+	x := randomElement(n, m.PQ)
+	y := randomElement(n, m.PQ)
+	want := negacyclic.Karatsuba(x, y).Mod(m.PQ)
+	got := m.Mul(x, y).Mod(m.PQ)
+	for i := range got.Coeffs {
+		if got.Coeffs[i].Cmp(want.Coeffs[i]) != 0 {
+			t.Fatal("incorrect result modulo the product of the primes")
+		}
+	}
+}
+
+func testNTTCRTPrefix(t *testing.T) {
+	n := 1 << 8
+	m := negacyclic.NewCRTMultiplier(n, chainOfPrimes(n, 60, 60, 60, 60, 60)...)
+	prefix := m.Prefix(3)
+	x := randomElement(n, prefix.PQ)
+	y := randomElement(n, prefix.PQ)
+	want := prefix.Mul(x, y)
+
+	// Evaluation forms modulo all the primes reduce to the prefix ones.
+	xNTT, yNTT := m.ToNTT(x), m.ToNTT(y)
+	for _, pol := range []*negacyclic.Polynomial{xNTT, yNTT} {
+		for _, coeff := range pol.Coeffs {
+			coeff.Mod(coeff, prefix.PQ)
+		}
+	}
+	got := prefix.FromNTT(prefix.Hadamard(xNTT, yNTT))
+	for i := range got.Coeffs {
+		if got.Coeffs[i].Cmp(want.Coeffs[i]) != 0 {
+			t.Fatal("incorrect product after dropping primes")
+		}
+	}
+}
+
+// chainOfPrimes returns NTT-friendly primes of the given bit lengths, where
+// repeated bit lengths give distinct word-sized primes.
+func chainOfPrimes(n int, bitLens ...int) []*big.Int {
+	seen := make(map[int]int)
+	primes := make([]*big.Int, len(bitLens))
+	for i, bitLen := range bitLens {
+		if bitLen > negacyclic.MaxRNSModulusBitLen {
+			primes[i] = negacyclic.RLWEPrime(bitLen, 2*n)
+			continue
+		}
+		words := negacyclic.RNSPrimes(bitLen, 2*n, seen[bitLen]+1)
+		primes[i] = new(big.Int).SetUint64(words[seen[bitLen]])
+		seen[bitLen]++
+	}
+	return primes
+}
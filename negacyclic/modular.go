@@ -0,0 +1,173 @@
+package negacyclic
+
+import (
+	"math/big"
+	"math/bits"
+)
+
+// MaxWordModulusBitLen is the largest bit length of a word-sized modulus. It
+// leaves two spare bits, so that lazy reductions can keep values in [0, 4q).
+const MaxWordModulusBitLen = 61
+
+// Modulus contains the precomputations for arithmetic modulo an odd word-sized
+// modulus q < 2^61, with Barrett and Montgomery reductions.
+//
+// Barrett reduction (see Mul and Reduce) works on operands in standard form.
+// Montgomery reduction (see MRed) works on operands in Montgomery form, i.e.
+// a*2^64 mod q (see MForm), and is preferable when one of the operands is
+// fixed and can be converted once.
+type Modulus struct {
+	Q        uint64
+	barrettH uint64 // ⌊2^128 / q⌋ = barrettH * 2^64 + barrettL
+	barrettL uint64
+	montInv  uint64 // -q^{-1} mod 2^64
+}
+
+// NewModulus creates and returns a Modulus with the given parameters, after
+// proper sanitization.
+func NewModulus(q uint64) *Modulus {
+	if q&1 == 0 || bits.Len64(q) > MaxWordModulusBitLen {
+		panic("modulus must be odd and fit in 61 bits")
+	}
+	m := new(Modulus)
+	m.Q = q
+	// ⌊2^128 / q⌋ is computed with two long divisions.
+	var rem uint64
+	m.barrettH, rem = bits.Div64(1, 0, q)
+	m.barrettL, _ = bits.Div64(rem, 0, q)
+	// Newton's iteration: each step doubles the number of correct bits.
+	inv := q
+	for i := 0; i < 5; i++ {
+		inv *= 2 - q*inv
+	}
+	m.montInv = -inv
+	return m
+}
+
+// Add returns a + b mod q, for a, b < q.
+func (m *Modulus) Add(a, b uint64) uint64 {
+	return addMod(a, b, m.Q)
+}
+
+// Sub returns a - b mod q, for a, b < q.
+func (m *Modulus) Sub(a, b uint64) uint64 {
+	return subMod(a, b, m.Q)
+}
+
+// Neg returns -a mod q, for a < q.
+func (m *Modulus) Neg(a uint64) uint64 {
+	return subMod(0, a, m.Q)
+}
+
+// Reduce returns a mod q, for any word a.
+func (m *Modulus) Reduce(a uint64) uint64 {
+	return m.barrettReduce(0, a)
+}
+
+// Mul returns a * b mod q, for a, b < q, using Barrett reduction.
+func (m *Modulus) Mul(a, b uint64) uint64 {
+	hi, lo := bits.Mul64(a, b)
+	return m.barrettReduce(hi, lo)
+}
+
+// MForm returns a*2^64 mod q, the Montgomery form of a < q.
+func (m *Modulus) MForm(a uint64) uint64 {
+	return m.barrettReduce(a, 0)
+}
+
+// InvMForm returns a*2^{-64} mod q, the standard form of a < q given in
+// Montgomery form.
+func (m *Modulus) InvMForm(a uint64) uint64 {
+	return m.montgomeryReduce(0, a)
+}
+
+// MRed returns a*b*2^{-64} mod q, for a, b < q, using Montgomery reduction.
+// Notice that MRed(a, MForm(b)) = a*b mod q.
+func (m *Modulus) MRed(a, b uint64) uint64 {
+	hi, lo := bits.Mul64(a, b)
+	return m.montgomeryReduce(hi, lo)
+}
+
+// Shoup returns ⌊w*2^64/q⌋, the precomputation associated to a constant
+// w < q for MulShoup.
+func (m *Modulus) Shoup(w uint64) uint64 {
+	quo, _ := bits.Div64(w, 0, m.Q)
+	return quo
+}
+
+// MulShoup returns a * w mod q, for a < 2^64, w < q and wShoup = Shoup(w).
+func (m *Modulus) MulShoup(a, w, wShoup uint64) uint64 {
+	r := mulShoupLazy(a, w, wShoup, m.Q)
+	if r >= m.Q {
+		r -= m.Q
+	}
+	return r
+}
+
+//
+// Internal functions
+//
+
+// barrettReduce returns (hi*2^64 + lo) mod q, for hi < q.
+func (m *Modulus) barrettReduce(hi, lo uint64) uint64 {
+	q := m.Q
+	// The quotient ⌊x*⌊2^128/q⌋/2^128⌋ is computed dropping the lowest
+	// partial product; it underestimates the true quotient by at most 3.
+	midHi1, midLo1 := bits.Mul64(hi, m.barrettL)
+	midHi2, midLo2 := bits.Mul64(lo, m.barrettH)
+	lowHi, _ := bits.Mul64(lo, m.barrettL)
+	sum, carry1 := bits.Add64(midLo1, midLo2, 0)
+	_, carry2 := bits.Add64(sum, lowHi, 0)
+	quo := hi*m.barrettH + midHi1 + midHi2 + carry1 + carry2
+	r := lo - quo*q
+	for r >= q {
+		r -= q
+	}
+	return r
+}
+
+// montgomeryReduce returns (hi*2^64 + lo)*2^{-64} mod q, for hi < q.
+func (m *Modulus) montgomeryReduce(hi, lo uint64) uint64 {
+	q := m.Q
+	u := lo * m.montInv
+	uqHi, uqLo := bits.Mul64(u, q)
+	_, carry := bits.Add64(uqLo, lo, 0)
+	r := hi + uqHi + carry
+	if r >= q {
+		r -= q
+	}
+	return r
+}
+
+// mulShoupLazy returns a * w mod q in [0, 2q), for a < 2^64, w < q and wShoup =
+// ⌊w*2^64/q⌋ (see Harvey, FASTER ARITHMETIC FOR NUMBER-THEORETIC TRANSFORMS).
+func mulShoupLazy(a, w, wShoup, q uint64) uint64 {
+	quo, _ := bits.Mul64(a, wShoup)
+	return a*w - quo*q
+}
+
+// addMod returns a + b mod q, for a, b < q.
+func addMod(a, b, q uint64) uint64 {
+	c := a + b
+	if c >= q {
+		c -= q
+	}
+	return c
+}
+
+// subMod returns a - b mod q, for a, b < q.
+func subMod(a, b, q uint64) uint64 {
+	if a >= b {
+		return a - b
+	}
+	return a + q - b
+}
+
+// wordModulus returns the modulus as a word, and true, if it is small enough
+// for the word-sized kernels.
+func wordModulus(mod *big.Int) (uint64, bool) {
+	if mod.Sign() <= 0 || mod.BitLen() > MaxWordModulusBitLen {
+		return 0, false
+	}
+	return mod.Uint64(), true
+}
@@ -0,0 +1,95 @@
+package negacyclic_test
+
+import (
+	"math/big"
+	"math/rand"
+	"testing"
+
+	"ckks/negacyclic"
+)
+
+var testWordModuli = []uint64{
+	12289,
+	negacyclic.RLWEPrime(30, 1<<14).Uint64(),
+	negacyclic.RLWEPrime(50, 1<<14).Uint64(),
+	negacyclic.RLWEPrime(61, 1<<14).Uint64(),
+	1<<61 - 1,
+}
+
+func TestModularArithmetic(t *testing.T) {
+	t.Run("barrett", testBarrett)
+	t.Run("montgomery", testMontgomery)
+	t.Run("shoup", testShoup)
+}
+
+func testBarrett(t *testing.T) {
+	for _, q := range testWordModuli {
+		m := negacyclic.NewModulus(q)
+		for i := 0; i < 1000; i++ {
+			a, b := rand.Uint64()%q, rand.Uint64()%q
+			if got, want := m.Mul(a, b), mulModBig(a, b, q); got != want {
+				t.Fatalf("%d * %d mod %d: got %d, want %d", a, b, q, got, want)
+			}
+			c := rand.Uint64()
+			if got, want := m.Reduce(c), c%q; got != want {
+				t.Fatalf("%d mod %d: got %d, want %d", c, q, got, want)
+			}
+		}
+	}
+}
+
+func testMontgomery(t *testing.T) {
+	for _, q := range testWordModuli {
+		m := negacyclic.NewModulus(q)
+		for i := 0; i < 1000; i++ {
+			a, b := rand.Uint64()%q, rand.Uint64()%q
+			if got, want := m.MRed(a, m.MForm(b)), mulModBig(a, b, q); got != want {
+				t.Fatalf("%d * %d mod %d: got %d, want %d", a, b, q, got, want)
+			}
+			if got := m.InvMForm(m.MForm(a)); got != a {
+				t.Fatalf("Montgomery form roundtrip: got %d, want %d", got, a)
+			}
+		}
+	}
+}
+
+func testShoup(t *testing.T) {
+	for _, q := range testWordModuli {
+		m := negacyclic.NewModulus(q)
+		for i := 0; i < 1000; i++ {
+			a, w := rand.Uint64(), rand.Uint64()%q
+			got := m.MulShoup(a, w, m.Shoup(w))
+			if want := mulModBig(a%q, w, q); got != want {
+				t.Fatalf("%d * %d mod %d: got %d, want %d", a, w, q, got, want)
+			}
+		}
+	}
+}
+
+func BenchmarkModularArithmetic(b *testing.B) {
+	q := negacyclic.RLWEPrime(61, 1<<14).Uint64()
+	m := negacyclic.NewModulus(q)
+	x, y := rand.Uint64()%q, rand.Uint64()%q
+	yMont, yShoup := m.MForm(y), m.Shoup(y)
+	b.Run("barrett", func(b *testing.B) {
+		for i := 0; i < b.N; i++ {
+			x = m.Mul(x, y)
+		}
+	})
+	b.Run("montgomery", func(b *testing.B) {
+		for i := 0; i < b.N; i++ {
+			x = m.MRed(x, yMont)
+		}
+	})
+	b.Run("shoup", func(b *testing.B) {
+		for i := 0; i < b.N; i++ {
+			x = m.MulShoup(x, y, yShoup)
+		}
+	})
+}
+
+func mulModBig(a, b, q uint64) uint64 {
+	res := new(big.Int).SetUint64(a)
+	res.Mul(res, new(big.Int).SetUint64(b))
+	return res.Mod(res, new(big.Int).SetUint64(q)).Uint64()
+}
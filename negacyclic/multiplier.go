@@ -12,6 +12,7 @@ type Multiplier struct {
 	nInvQ              *big.Int
 	rootsBitReverse    []*big.Int
 	invRootsBitReverse []*big.Int
+	table              *nttTable // Word-sized transforms, if Mod is small
 }
 
 // NewMultiplier creates and returns a CRTMultiplier with the given parameters,
@@ -37,23 +38,23 @@ func NewMultiplier(n int, mod *big.Int) *Multiplier {
 	gInv := modularInverse(g, mod)
 	m.rootsBitReverse = rootsOfUnityBitReverse(n, g, mod)
 	m.invRootsBitReverse = rootsOfUnityBitReverse(n, gInv, mod)
+	if q, ok := wordModulus(mod); ok {
+		m.table = newNTTTable(n, q)
+	}
 	return m
 }
 
 // Mul computes the product of x and y in the corresponding negacyclic ring.
+// The operands can be in either form, and only the ones in coefficient form
+// are transformed. The result is in coefficient form.
 func (mul *Multiplier) Mul(x, y *Polynomial) *Polynomial {
 	if x.Deg() != y.Deg() {
 		panic("asymmetric multiplication call")
 	}
-	n := x.Deg()
-	a, b := NewPolynomial(n), NewPolynomial(n)
-	for i := 0; i < n; i++ {
-		a.Coeffs[i], b.Coeffs[i] = new(big.Int), new(big.Int)
-		a.Coeffs[i].Set(x.Coeffs[i])
-		b.Coeffs[i].Set(y.Coeffs[i])
+	if mul.table != nil {
+		return mul.mulWords(x, y)
 	}
-	mul.NTT(a)
-	mul.NTT(b)
+	a, b := mul.ToNTT(x), mul.ToNTT(y)
 	c := mul.Hadamard(a, b)
 	mul.INTT(c)
 	for _, coeff := range c.Coeffs {
@@ -61,3 +62,44 @@ func (mul *Multiplier) Mul(x, y *Polynomial) *Polynomial {
 	}
 	return c
 }
+
+// ToNTT returns the evaluation form of x. It returns x itself if it is already
+// in evaluation form, and a transformed copy otherwise.
+func (mul *Multiplier) ToNTT(x *Polynomial) *Polynomial {
+	if x.isNTT {
+		return x
+	}
+	a := x.Copy()
+	mul.NTT(a)
+	return a
+}
+
+// FromNTT returns the coefficient form of x, with coefficients in [0, Mod).
+// It returns x itself if it is already in coefficient form, and a transformed
+// copy otherwise.
+func (mul *Multiplier) FromNTT(x *Polynomial) *Polynomial {
+	if !x.isNTT {
+		return x
+	}
+	a := x.Copy()
+	mul.INTT(a)
+	return a
+}
+
+// mulWords is the word-sized version of Mul, for moduli of at most
+// MaxWordModulusBitLen bits.
+func (mul *Multiplier) mulWords(x, y *Polynomial) *Polynomial {
+	a := wordsFromCoeffs(x.Coeffs, mul.Mod)
+	b := wordsFromCoeffs(y.Coeffs, mul.Mod)
+	if !x.isNTT {
+		mul.table.forward(a)
+	}
+	if !y.isNTT {
+		mul.table.forward(b)
+	}
+	mul.table.hadamard(a, a, b)
+	mul.table.inverse(a)
+	c := NewPolynomial(mul.N)
+	setCoeffsFromWords(c.Coeffs, a)
+	return c
+}
@@ -11,6 +11,7 @@ func TestPolynomialMultiplication(t *testing.T) {
 	t.Run("karatsuba", testKaratsuba)
 	t.Run("nttNewHope", testNTT12289)
 	t.Run("nttMedium", testNTTMedium)
+	t.Run("nttEvaluationForm", testNTTEvaluationForm)
 }
 
 func testKaratsuba(t *testing.T) {
@@ -65,6 +66,26 @@ func testNTTMedium(t *testing.T) {
 	}
 }
 
+func testNTTEvaluationForm(t *testing.T) {
+	for _, bitLen := range []int{50, 100} {
+		n := 1 << 8
+		q := negacyclic.RLWEPrime(bitLen, 2*n)
+		m := negacyclic.NewMultiplier(n, q)
+		x := randomElement(n, q)
+		y := randomElement(n, q)
+		want := m.Mul(x, y)
+		got := m.Mul(m.ToNTT(x), m.ToNTT(y))
+		if got.IsNTT() {
+			t.Fatal("expected a product in coefficient form")
+		}
+		for i := range want.Coeffs {
+			if want.Coeffs[i].Cmp(got.Coeffs[i]) != 0 {
+				t.Fatal("incorrect product of operands in evaluation form")
+			}
+		}
+	}
+}
+
 func BenchmarkNegacyclicMultiplication(b *testing.B) {
 	b.Run("naive", benchNaiveMul)
 	b.Run("Karatsuba", benchKaratsubaMul)
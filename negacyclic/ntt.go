@@ -6,11 +6,19 @@ import (
 
 // NTT computes the Number-Theoretic Transform of the input vector (a[0], ...,
 // a[n-1) in the field F_q. It mutates the input vector with NTT(a) in
-// bit-reversed order. It is assumed that q = 1 mod 2n.
+// bit-reversed order, and marks it as in evaluation form. It is assumed that
+// q = 1 mod 2n.
 //
 // See Longa & Naehrig, SPEEDING UP THE NUMBER THEORETIC TRANSFORM FOR FASTER
 // IDEAL LATTICE-BASED CRYPTOGRAPHY.
 func (mul *Multiplier) NTT(a *Polynomial) {
+	a.isNTT = true
+	if mul.table != nil {
+		words := wordsFromCoeffs(a.Coeffs, mul.Mod)
+		mul.table.forward(words)
+		setCoeffsFromWords(a.Coeffs, words)
+		return
+	}
 	n := mul.N
 	q := mul.Mod
 	roots := mul.rootsBitReverse
@@ -37,8 +45,15 @@ func (mul *Multiplier) NTT(a *Polynomial) {
 
 // INTT is the inverse Number-Theoretic Transform based on the GS butterfly.
 // The output is in bit-reversed ordering, therefore, INTT(NTT(a)) = a in
-// standard ordering.
+// standard ordering. It marks the input as in coefficient form.
 func (mul *Multiplier) INTT(a *Polynomial) {
+	a.isNTT = false
+	if mul.table != nil {
+		words := wordsFromCoeffs(a.Coeffs, mul.Mod)
+		mul.table.inverse(words)
+		setCoeffsFromWords(a.Coeffs, words)
+		return
+	}
 	n := mul.N
 	q := mul.Mod
 	rootsInv := mul.invRootsBitReverse
@@ -69,15 +84,41 @@ func (mul *Multiplier) INTT(a *Polynomial) {
 	}
 }
 
-// Hadamard returns a polynomial `c` with `c[i] = a[i] * b[i] mod q`.
+// Hadamard returns a polynomial `c` with `c[i] = a[i] * b[i] mod q`. If a and
+// b are in evaluation form, then c is their product in evaluation form.
 func (mul *Multiplier) Hadamard(a, b *Polynomial) *Polynomial {
 	if a.Deg() != b.Deg() {
 		panic("asymmetric multiplication call")
 	}
 	c := NewPolynomial(a.Deg())
+	c.isNTT = true
 	for i := range a.Coeffs {
 		c.Coeffs[i] = new(big.Int)
 		c.Coeffs[i].Mul(a.Coeffs[i], b.Coeffs[i]).Mod(c.Coeffs[i], mul.Mod)
 	}
 	return c
 }
+
+// wordsFromCoeffs returns the coefficients reduced modulo mod, as words.
+func wordsFromCoeffs(coeffs []*big.Int, mod *big.Int) []uint64 {
+	words := make([]uint64, len(coeffs))
+	aux := new(big.Int)
+	for i, coeff := range coeffs {
+		if coeff.Sign() >= 0 && coeff.Cmp(mod) < 0 {
+			words[i] = coeff.Uint64()
+			continue
+		}
+		words[i] = aux.Mod(coeff, mod).Uint64()
+	}
+	return words
+}
+
+// setCoeffsFromWords sets coeffs[i] to words[i].
+func setCoeffsFromWords(coeffs []*big.Int, words []uint64) {
+	for i := range coeffs {
+		if coeffs[i] == nil {
+			coeffs[i] = new(big.Int)
+		}
+		coeffs[i].SetUint64(words[i])
+	}
+}
@@ -3,6 +3,7 @@ package negacyclic_test
 import (
 	"crypto/rand"
 	"math/big"
+	"math/bits"
 	"testing"
 
 	"ckks/negacyclic"
@@ -90,6 +91,9 @@ func TestFindRootOfUnityNewHope(t *testing.T) {
 
 func TestNTT(t *testing.T) {
 	t.Run("NTT_INTT_roundtrip", testNTTRoundtrip)
+	t.Run("NTT_INTT_roundtrip_61bits", testNTTRoundtripWord)
+	t.Run("NTT_evaluation_61bits", func(t *testing.T) { testNTTEvaluation(61, t) })
+	t.Run("NTT_evaluation_100bits", func(t *testing.T) { testNTTEvaluation(100, t) })
 }
 
 func testNTTRoundtrip(t *testing.T) {
@@ -113,8 +117,53 @@ func testNTTRoundtrip(t *testing.T) {
 	}
 }
 
+func testNTTRoundtripWord(t *testing.T) {
+	n := 1 << 12
+	q := negacyclic.RLWEPrime(61, 2*n)
+	m := negacyclic.NewMultiplier(n, q)
+	x := randomElement(n, q)
+
+	y := negacyclic.NewPolynomial(n)
+	for i := 0; i < n; i++ {
+		y.Coeffs[i].Set(x.Coeffs[i])
+	}
+
+	m.NTT(x)
+	m.INTT(x)
+	for i := 0; i < n; i++ {
+		if y.Coeffs[i].Cmp(x.Coeffs[i]) != 0 {
+			t.Fatal("NTT roundtrip failed")
+		}
+	}
+}
+
+// The NTT of x stores x(g^{2 rev(i) + 1}) at index i, for the primitive root g
+// found by FindPrimitiveRootOfUnity. This holds for both the word-sized and
+// the big integer transforms.
+func testNTTEvaluation(bitLen int, t *testing.T) {
+	n := 1 << 6
+	q := negacyclic.RLWEPrime(bitLen, 2*n)
+	m := negacyclic.NewMultiplier(n, q)
+	g := negacyclic.FindPrimitiveRootOfUnity(2*n, q)
+	x := randomElement(n, q)
+	want := make([]*big.Int, n)
+	for i := 0; i < n; i++ {
+		rev := bits.Reverse(uint(i)) >> uint(bits.UintSize-bits.Len(uint(n-1)))
+		root := new(big.Int).Exp(g, big.NewInt(int64(2*rev+1)), q)
+		want[i] = evaluate(x, root, q)
+	}
+	m.NTT(x)
+	for i := range want {
+		if want[i].Cmp(x.Coeffs[i]) != 0 {
+			t.Fatalf("NTT(x)[%d] = %s, want %s", i, x.Coeffs[i], want[i])
+		}
+	}
+}
+
 func BenchmarkNumberTheoreticTransform(b *testing.B) {
 	b.Run("newHope", benchNTTNewHope)
+	b.Run("8192-61bits", benchNTTWord)
+	b.Run("8192-62bits", benchNTTNotWord)
 	b.Run("2048-100bits", benchNTTMedium)
 	b.Run("32768-200bits", benchNTTLarge)
 	b.Run("NTT", benchMulNTT)
@@ -155,6 +204,38 @@ func benchNTTLarge(b *testing.B) {
 	}
 }
 
+func benchNTTWord(b *testing.B) {
+	n := 1 << 13
+	q := negacyclic.RLWEPrime(61, 2*n)
+	m := negacyclic.NewMultiplier(n, q)
+	x := randomElement(n, q)
+
+	for i := 0; i < b.N; i++ {
+		m.NTT(x)
+	}
+}
+
+func benchNTTNotWord(b *testing.B) {
+	n := 1 << 13
+	q := negacyclic.RLWEPrime(62, 2*n)
+	m := negacyclic.NewMultiplier(n, q)
+	x := randomElement(n, q)
+
+	for i := 0; i < b.N; i++ {
+		m.NTT(x)
+	}
+}
+
+func evaluate(x *negacyclic.Polynomial, point, q *big.Int) *big.Int {
+	res, pow := new(big.Int), big.NewInt(1)
+	aux := new(big.Int)
+	for _, coeff := range x.Coeffs {
+		res.Add(res, aux.Mul(coeff, pow))
+		pow.Mul(pow, point).Mod(pow, q)
+	}
+	return res.Mod(res, q)
+}
+
 func randomElement(dim int, q *big.Int) *negacyclic.Polynomial {
 	pol := negacyclic.NewPolynomial(dim)
 	var err error
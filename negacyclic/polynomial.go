@@ -4,8 +4,10 @@
 // is a power of 2.
 //
 // It defines the negacyclic.Polynomial object for polynomials with
-// arbitrary-precision coefficients, and the negacyclic.Vector object, for
-// polynomials with small (e.g. int) coefficients.
+// arbitrary-precision coefficients, the negacyclic.Vector object, for
+// polynomials with small (e.g. int) coefficients, and the
+// negacyclic.RNSPolynomial object, for polynomials represented by their
+// residues modulo a product of word-sized primes (see RNSRing).
 //
 // Arithmetic in `R_p` is implemented with the Number Theoretic Transform,
 // arithmetic in `R_{pq^l}` is implemented with the CRT and Hensel's lifting,
@@ -18,9 +20,14 @@ import (
 )
 
 // Polynomial is a slice of big integers, representing a polynomial in a
-// negacyclic ring.
+// negacyclic ring. A polynomial is either in coefficient form, or in
+// evaluation (NTT) form with respect to some multiplier (see
+// Multiplier.ToNTT). In evaluation form, addition, subtraction, negation,
+// scaling and reduction are still available, and products are computed
+// coefficient-wise by the multiplier (see Multiplier.Hadamard).
 type Polynomial struct {
 	Coeffs []*big.Int
+	isNTT  bool
 }
 
 // Deg returns the degree of p. It returns 0 on the 0 polynomial.
@@ -28,6 +35,20 @@ func (p *Polynomial) Deg() int {
 	return len(p.Coeffs)
 }
 
+// IsNTT returns true iff p is in evaluation (NTT) form.
+func (p *Polynomial) IsNTT() bool {
+	return p.isNTT
+}
+
+// Copy returns a deep copy of p, in the same form as p.
+func (p *Polynomial) Copy() *Polynomial {
+	coeffs := make([]*big.Int, len(p.Coeffs))
+	for i := range coeffs {
+		coeffs[i] = new(big.Int).Set(p.Coeffs[i])
+	}
+	return &Polynomial{Coeffs: coeffs, isNTT: p.isNTT}
+}
+
 // String is the stringer method for `Polynomial`.
 func (p *Polynomial) String() string {
 	str := "["
@@ -78,6 +99,9 @@ func (p *Polynomial) ScaleNearest(scale *big.Int) *Polynomial {
 	if scale.Cmp(big.NewInt(0)) == 0 {
 		panic("division by zero")
 	}
+	if p.isNTT {
+		panic("cannot scale a polynomial in NTT form")
+	}
 	aux, denom, quo := new(big.Float), new(big.Float), new(big.Float)
 	denom.SetInt(scale)
 	result := NewPolynomial(p.Deg())
@@ -100,6 +124,9 @@ func Karatsuba(p, q *Polynomial) *Polynomial {
 	if !isPowerOfTwo(p.Deg()) || !isPowerOfTwo(q.Deg()) {
 		panic("Karatsuba only implemented for power of two degrees")
 	}
+	if p.isNTT || q.isNTT {
+		panic("Karatsuba expects polynomials in coefficient form")
+	}
 	karat := karatsubaRec(p.Coeffs, q.Coeffs)
 	for i := 0; i < p.Deg(); i++ {
 		karat[i].Sub(karat[i], karat[i+p.Deg()])
@@ -159,6 +186,9 @@ func MulSimple(p, q interface{}) *Polynomial {
 
 	polVec := pPolOk && qVecOk
 	vecVec := pVecOk && qVecOk
+	if polVec && pPol.isNTT {
+		panic("MulSimple expects polynomials in coefficient form")
+	}
 	if polVec {
 		return cycMulNaivePolTerVec(pPol, qVec)
 	} else if vecVec {
@@ -183,10 +213,11 @@ func (p *Polynomial) Scale(scalar *big.Int) {
 
 // Sub returns p - q.
 func Sub(p, q *Polynomial) *Polynomial {
-	if p.Deg() != q.Deg() {
+	if p.Deg() != q.Deg() || p.isNTT != q.isNTT {
 		panic("incompatible subtraction")
 	}
 	result := NewPolynomial(p.Deg())
+	result.isNTT = p.isNTT
 
 	for i := range result.Coeffs {
 		result.Coeffs[i] = new(big.Int).Sub(p.Coeffs[i], q.Coeffs[i])
@@ -278,10 +309,11 @@ func cycMulNaivePolTerVec(p *Polynomial, v *Vector) *Polynomial {
 }
 
 func addPolPol(p, q *Polynomial) *Polynomial {
-	if p.Deg() != q.Deg() {
+	if p.Deg() != q.Deg() || p.isNTT != q.isNTT {
 		panic("incompatible addition")
 	}
 	result := NewPolynomial(p.Deg())
+	result.isNTT = p.isNTT
 
 	for i := range result.Coeffs {
 		result.Coeffs[i] = new(big.Int).Add(p.Coeffs[i], q.Coeffs[i])
@@ -290,7 +322,7 @@ func addPolPol(p, q *Polynomial) *Polynomial {
 }
 
 func addPolVec(p *Polynomial, v *Vector) *Polynomial {
-	if p.Deg() != v.Len() {
+	if p.Deg() != v.Len() || p.isNTT {
 		panic("incompatible addition")
 	}
 	dim := p.Deg()
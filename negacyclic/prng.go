@@ -0,0 +1,112 @@
+package negacyclic
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"encoding/binary"
+	"math/big"
+	"math/bits"
+)
+
+// SeedSize is the size, in bytes, of the seeds of PRNG.
+const SeedSize = 32
+
+// PRNG is a deterministic expandable pseudo-random generator: it outputs the
+// AES-256-CTR keystream keyed by its seed, with a zero IV. Two generators with
+// the same seed produce the same stream, so that uniform polynomials can be
+// transmitted as their seed (see UniformModFromSeed).
+type PRNG struct {
+	stream cipher.Stream
+}
+
+// NewSeed returns a fresh seed sampled with a cryptographic random generator.
+func NewSeed() []byte {
+	seed := make([]byte, SeedSize)
+	if _, err := rand.Read(seed); err != nil {
+		panic(err)
+	}
+	return seed
+}
+
+// NewPRNG returns the generator expanding the given seed, which must have
+// length SeedSize.
+func NewPRNG(seed []byte) *PRNG {
+	if len(seed) != SeedSize {
+		panic("PRNG seed must have length SeedSize")
+	}
+	block, err := aes.NewCipher(seed)
+	if err != nil {
+		panic(err)
+	}
+	return &PRNG{stream: cipher.NewCTR(block, make([]byte, aes.BlockSize))}
+}
+
+// Read fills p with the next bytes of the keystream. It never fails.
+func (prng *PRNG) Read(p []byte) (int, error) {
+	for i := range p {
+		p[i] = 0
+	}
+	prng.stream.XORKeyStream(p, p)
+	return len(p), nil
+}
+
+// UniformModFromSeed samples a polynomial of given degree with uniform
+// coefficients in Z/qZ, deterministically from the seed. Each coefficient is
+// drawn by rejection: the bit length of q is read from the stream, and the
+// value is discarded if it is not below q.
+func UniformModFromSeed(deg int, q *big.Int, seed []byte) []*big.Int {
+	if q.Sign() <= 0 {
+		panic("modulus must be positive")
+	}
+	prng := NewPRNG(seed)
+	bitLen := q.BitLen()
+	buf := make([]byte, (bitLen+7)/8)
+	mask := byte(0xff >> uint(8*len(buf)-bitLen))
+	pol := make([]*big.Int, deg)
+	for i := 0; i < deg; i++ {
+		pol[i] = new(big.Int)
+		for {
+			prng.Read(buf)
+			buf[0] &= mask
+			pol[i].SetBytes(buf)
+			if pol[i].Cmp(q) < 0 {
+				break
+			}
+		}
+	}
+	return pol
+}
+
+// UniformRNSFromSeed samples `count` polynomials of given degree whose
+// residues are uniform modulo each of the given word-sized primes, i.e. whose
+// coefficients are uniform modulo their product, deterministically from the
+// seed. As the transform of a uniform polynomial is uniform, the polynomials
+// are returned in evaluation form. Each residue is drawn by rejection from the
+// bit length of its prime, as in UniformModFromSeed.
+func UniformRNSFromSeed(deg int, moduli []uint64, count int, seed []byte) []*RNSPolynomial {
+	prng := NewPRNG(seed)
+	var buf [8]byte
+	pols := make([]*RNSPolynomial, count)
+	for k := range pols {
+		coeffs := make([][]uint64, len(moduli))
+		for i, q := range moduli {
+			if q < 2 {
+				panic("modulus must be larger than 1")
+			}
+			mask := uint64(1)<<uint(bits.Len64(q-1)) - 1
+			coeffs[i] = make([]uint64, deg)
+			for j := range coeffs[i] {
+				for {
+					prng.Read(buf[:])
+					coeffs[i][j] = binary.BigEndian.Uint64(buf[:]) & mask
+					if coeffs[i][j] < q {
+						break
+					}
+				}
+			}
+		}
+		pols[k] = &RNSPolynomial{Coeffs: coeffs, isNTT: true}
+	}
+	return pols
+}
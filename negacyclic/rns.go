@@ -0,0 +1,473 @@
+package negacyclic
+
+import (
+	"errors"
+	"math/big"
+	"math/bits"
+)
+
+// MaxRNSModulusBitLen is the largest bit length allowed for the primes of an
+// RNSRing.
+const MaxRNSModulusBitLen = 60
+
+// RNSRing handles arithmetic in Z_Q[X]/(X^n+1), where Q = q_0 * ... * q_{k-1}
+// is a product of distinct word-sized primes with q_i = 1 mod 2n. Elements of
+// the ring are represented by their residues modulo each q_i (see
+// RNSPolynomial), so that all the arithmetic is carried on machine words.
+type RNSRing struct {
+	N      int
+	Moduli []uint64
+	Q      *big.Int // Product of all the moduli
+	tables []*nttTable
+
+	// CRT reconstruction: x = sum_i [x_i * qHatInv_i]_{q_i} * qHat_i mod Q.
+	qHat    []*big.Int // Q / q_i
+	qHatInv []uint64   // (Q / q_i)^{-1} mod q_i
+
+	// Rescaling: q_{k-1}^{-1} mod q_i for i < k-1, and its Shoup constant.
+	lastInv, lastInvShoup []uint64
+}
+
+// RNSPolynomial is a polynomial in an RNSRing, where Coeffs[i][j] is the j-th
+// coefficient reduced modulo the i-th prime of the ring. As a Polynomial, it is
+// either in coefficient form or in evaluation (NTT) form.
+type RNSPolynomial struct {
+	Coeffs [][]uint64
+	isNTT  bool
+}
+
+// NewRNSRing creates and returns an RNSRing with the given parameters, after
+// proper sanitization.
+func NewRNSRing(n int, moduli []uint64) *RNSRing {
+	if !isPowerOfTwo(n) {
+		panic("RNS ring expects `n` power of two")
+	}
+	if len(moduli) == 0 {
+		panic("RNS ring expects at least one modulus")
+	}
+	r := new(RNSRing)
+	r.N = n
+	r.Moduli = make([]uint64, len(moduli))
+	r.tables = make([]*nttTable, len(moduli))
+	seen := make(map[uint64]bool)
+	for i, q := range moduli {
+		if bits.Len64(q) > MaxRNSModulusBitLen {
+			panic("RNS modulus exceeds the maximal bit length")
+		}
+		if seen[q] {
+			panic("RNS ring expects distinct moduli")
+		}
+		seen[q] = true
+		r.Moduli[i] = q
+		r.tables[i] = newNTTTable(n, q)
+	}
+	r.setCRTConstants()
+	return r
+}
+
+// Prefix returns the ring modulo the product of the first `count` primes,
+// sharing the transforms of r. This drops the last primes, e.g. when rescaling
+// (see DivRoundByLastModulus).
+func (r *RNSRing) Prefix(count int) *RNSRing {
+	if count < 1 || count > len(r.Moduli) {
+		panic("invalid number of primes")
+	}
+	prefix := &RNSRing{
+		N:      r.N,
+		Moduli: r.Moduli[:count],
+		tables: r.tables[:count],
+	}
+	prefix.setCRTConstants()
+	return prefix
+}
+
+// Select returns the ring modulo the product of the primes of r at the given
+// indices, in that order, sharing the transforms of r. For instance, the ring
+// of the first primes of r and of its last one extends the former with the
+// latter (see LiftLimb).
+func (r *RNSRing) Select(indices []int) *RNSRing {
+	sub := &RNSRing{
+		N:      r.N,
+		Moduli: make([]uint64, len(indices)),
+		tables: make([]*nttTable, len(indices)),
+	}
+	for i, index := range indices {
+		sub.Moduli[i] = r.Moduli[index]
+		sub.tables[i] = r.tables[index]
+	}
+	if len(sub.Moduli) == 0 {
+		panic("RNS ring expects at least one modulus")
+	}
+	sub.setCRTConstants()
+	return sub
+}
+
+// RNSPrimes returns `count` distinct primes of given bit length satisfying
+// q = 1 mod n, in increasing order. The first prime is RLWEPrime(bitLen, n).
+// These primes are not sampled with a cryptographic random generator and MUST
+// NOT be used as secret values. It panics if there are not enough such primes
+// (see FindRNSPrimes).
+func RNSPrimes(bitLen, n, count int) []uint64 {
+	primes, err := FindRNSPrimes(bitLen, n, count)
+	if err != nil {
+		panic(err.Error())
+	}
+	return primes
+}
+
+// FindRNSPrimes is as RNSPrimes, but returns an error instead of panicking if
+// there are fewer than `count` primes of the given bit length satisfying
+// q = 1 mod n.
+func FindRNSPrimes(bitLen, n, count int) ([]uint64, error) {
+	if bitLen > MaxRNSModulusBitLen {
+		return nil, errors.New("RNS prime exceeds the maximal bit length")
+	}
+	if bitLen < bits.Len(uint(n)) {
+		return nil, errors.New("RNS prime must be >= n (bitLen too small)")
+	}
+	primes := make([]uint64, 0, count)
+	prime := RLWEPrime(bitLen, n)
+	dim := big.NewInt(int64(n))
+	for len(primes) < count {
+		if prime.BitLen() > bitLen {
+			return nil, errors.New("not enough RNS primes of the given bit length")
+		}
+		primes = append(primes, prime.Uint64())
+		prime = new(big.Int).Add(prime, dim)
+		for !prime.ProbablyPrime(32) {
+			prime.Add(prime, dim)
+		}
+	}
+	return primes, nil
+}
+
+// Limbs returns the number of primes of the ring.
+func (r *RNSRing) Limbs() int {
+	return len(r.Moduli)
+}
+
+// NewPolynomial allocates and returns the zero polynomial of the ring.
+func (r *RNSRing) NewPolynomial() *RNSPolynomial {
+	coeffs := make([][]uint64, len(r.Moduli))
+	for i := range coeffs {
+		coeffs[i] = make([]uint64, r.N)
+	}
+	return &RNSPolynomial{Coeffs: coeffs}
+}
+
+// NewRNSPolynomial returns the polynomial with the given residues, in
+// evaluation form if isNTT is true. The residues are not copied.
+func NewRNSPolynomial(coeffs [][]uint64, isNTT bool) *RNSPolynomial {
+	return &RNSPolynomial{Coeffs: coeffs, isNTT: isNTT}
+}
+
+// Select returns the residues of x modulo its primes at the given indices, in
+// the form of x, as a polynomial of the ring Select(indices). The residues are
+// shared with x.
+func (x *RNSPolynomial) Select(indices []int) *RNSPolynomial {
+	z := &RNSPolynomial{Coeffs: make([][]uint64, len(indices)), isNTT: x.isNTT}
+	for i, index := range indices {
+		z.Coeffs[i] = x.Coeffs[index]
+	}
+	return z
+}
+
+// IsNTT returns true iff x is in evaluation (NTT) form.
+func (x *RNSPolynomial) IsNTT() bool {
+	return x.isNTT
+}
+
+// Copy returns a deep copy of x, in the same form as x.
+func (x *RNSPolynomial) Copy() *RNSPolynomial {
+	coeffs := make([][]uint64, len(x.Coeffs))
+	for i := range coeffs {
+		coeffs[i] = make([]uint64, len(x.Coeffs[i]))
+		copy(coeffs[i], x.Coeffs[i])
+	}
+	return &RNSPolynomial{Coeffs: coeffs, isNTT: x.isNTT}
+}
+
+// FromPolynomial returns the residues modulo each prime of the ring, of the
+// given polynomial with arbitrary-precision coefficients. The result is in the
+// same form as p: the evaluation form of a Polynomial modulo Q is the CRT
+// combination of its evaluation forms modulo each prime (see CRTMultiplier).
+func (r *RNSRing) FromPolynomial(p *Polynomial) *RNSPolynomial {
+	if p.Deg() != r.N {
+		panic("incompatible conversion to RNS")
+	}
+	x := r.NewPolynomial()
+	x.isNTT = p.isNTT
+	aux := new(big.Int)
+	for i, q := range r.Moduli {
+		bigQ := new(big.Int).SetUint64(q)
+		for j, coeff := range p.Coeffs {
+			x.Coeffs[i][j] = aux.Mod(coeff, bigQ).Uint64()
+		}
+	}
+	return x
+}
+
+// ToPolynomial reconstructs x with the CRT, and returns the polynomial whose
+// coefficients are the representants modulo Q lying in (-Q/2, Q/2]. The result
+// is in the same form as x.
+func (r *RNSRing) ToPolynomial(x *RNSPolynomial) *Polynomial {
+	r.checkLimbs(x)
+	p := NewPolynomial(r.N)
+	p.isNTT = x.isNTT
+	aux := new(big.Int)
+	for j := 0; j < r.N; j++ {
+		coeff := p.Coeffs[j]
+		for i := range r.Moduli {
+			aux.SetUint64(r.tables[i].mod.Mul(x.Coeffs[i][j], r.qHatInv[i]))
+			aux.Mul(aux, r.qHat[i])
+			coeff.Add(coeff, aux)
+		}
+	}
+	return p.Mod(r.Q)
+}
+
+// DivRoundByLastModulus returns ⌊x/q_{k-1}⌉, where q_{k-1} is the last prime
+// of the ring, as a polynomial of Prefix(k-1). The input must be in
+// coefficient form.
+func (r *RNSRing) DivRoundByLastModulus(x *RNSPolynomial) *RNSPolynomial {
+	r.checkLimbs(x)
+	if x.isNTT {
+		panic("division expects a polynomial in coefficient form")
+	}
+	last := len(r.Moduli) - 1
+	if last == 0 {
+		panic("division expects at least two primes")
+	}
+	// ⌊x/q⌉ = (x + h - [x + h]_q) / q, for h = ⌊q/2⌋ and [.]_q in [0, q).
+	qLast := r.Moduli[last]
+	half := qLast >> 1
+	rem := make([]uint64, r.N)
+	for j, coeff := range x.Coeffs[last] {
+		rem[j] = addMod(coeff, half, qLast)
+	}
+	z := &RNSPolynomial{Coeffs: make([][]uint64, last)}
+	for i, table := range r.tables[:last] {
+		mod := table.mod
+		inv, invShoup := r.lastInv[i], r.lastInvShoup[i]
+		halfMod := mod.Reduce(half)
+		z.Coeffs[i] = make([]uint64, r.N)
+		for j, coeff := range x.Coeffs[i] {
+			v := mod.Sub(mod.Add(coeff, halfMod), mod.Reduce(rem[j]))
+			z.Coeffs[i][j] = mod.MulShoup(v, inv, invShoup)
+		}
+	}
+	return z
+}
+
+// LiftLimb returns the polynomial of r whose coefficients are the residues
+// of limb modulo q, lifted to (-q/2, q/2], and reduced modulo each prime of r.
+// The result is in coefficient form, as limb must be. This extends a limb of an
+// RNS polynomial to the primes of r, e.g. to decompose a polynomial by prime
+// for key switching.
+func (r *RNSRing) LiftLimb(limb []uint64, q uint64) *RNSPolynomial {
+	if len(limb) != r.N {
+		panic("limb and ring have different dimensions")
+	}
+	half := q >> 1
+	z := r.NewPolynomial()
+	for i, table := range r.tables {
+		mod := table.mod
+		if mod.Q == q {
+			copy(z.Coeffs[i], limb)
+			continue
+		}
+		for j, coeff := range limb {
+			if coeff > half {
+				z.Coeffs[i][j] = mod.Neg(mod.Reduce(q - coeff))
+			} else {
+				z.Coeffs[i][j] = mod.Reduce(coeff)
+			}
+		}
+	}
+	return z
+}
+
+// DropLastModuli returns the residues of x modulo the primes of r, for x with
+// more limbs, e.g. in a ring r is a Prefix of. This reduces x modulo the
+// product of the primes of r, in either form.
+func (r *RNSRing) DropLastModuli(x *RNSPolynomial) *RNSPolynomial {
+	if len(x.Coeffs) < len(r.Moduli) {
+		panic("RNS polynomial has fewer limbs than the ring")
+	}
+	z := &RNSPolynomial{Coeffs: make([][]uint64, len(r.Moduli)), isNTT: x.isNTT}
+	for i := range z.Coeffs {
+		z.Coeffs[i] = append([]uint64(nil), x.Coeffs[i]...)
+	}
+	r.checkLimbs(z)
+	return z
+}
+
+// Add returns x + y.
+func (r *RNSRing) Add(x, y *RNSPolynomial) *RNSPolynomial {
+	r.checkDomains(x, y)
+	z := r.NewPolynomial()
+	z.isNTT = x.isNTT
+	for i, q := range r.Moduli {
+		for j := 0; j < r.N; j++ {
+			z.Coeffs[i][j] = addMod(x.Coeffs[i][j], y.Coeffs[i][j], q)
+		}
+	}
+	return z
+}
+
+// Sub returns x - y.
+func (r *RNSRing) Sub(x, y *RNSPolynomial) *RNSPolynomial {
+	r.checkDomains(x, y)
+	z := r.NewPolynomial()
+	z.isNTT = x.isNTT
+	for i, q := range r.Moduli {
+		for j := 0; j < r.N; j++ {
+			z.Coeffs[i][j] = subMod(x.Coeffs[i][j], y.Coeffs[i][j], q)
+		}
+	}
+	return z
+}
+
+// Neg returns -x.
+func (r *RNSRing) Neg(x *RNSPolynomial) *RNSPolynomial {
+	r.checkLimbs(x)
+	z := r.NewPolynomial()
+	z.isNTT = x.isNTT
+	for i, q := range r.Moduli {
+		for j := 0; j < r.N; j++ {
+			z.Coeffs[i][j] = subMod(0, x.Coeffs[i][j], q)
+		}
+	}
+	return z
+}
+
+// MulScalar returns c * x, for an arbitrary integer c. The result is in the
+// same form as x.
+func (r *RNSRing) MulScalar(x *RNSPolynomial, c *big.Int) *RNSPolynomial {
+	r.checkLimbs(x)
+	z := r.NewPolynomial()
+	z.isNTT = x.isNTT
+	aux := new(big.Int)
+	for i, q := range r.Moduli {
+		mod := r.tables[i].mod
+		ci := aux.Mod(c, new(big.Int).SetUint64(q)).Uint64()
+		ciShoup := mod.Shoup(ci)
+		for j := 0; j < r.N; j++ {
+			z.Coeffs[i][j] = mod.MulShoup(x.Coeffs[i][j], ci, ciShoup)
+		}
+	}
+	return z
+}
+
+// Mul computes the product of x and y in the ring. The operands can be in
+// either form, and only the ones in coefficient form are transformed. The
+// result is in coefficient form.
+func (r *RNSRing) Mul(x, y *RNSPolynomial) *RNSPolynomial {
+	c := r.Hadamard(r.ToNTT(x), r.ToNTT(y))
+	r.INTT(c)
+	return c
+}
+
+// ToNTT returns the evaluation form of x. It returns x itself if it is already
+// in evaluation form, and a transformed copy otherwise.
+func (r *RNSRing) ToNTT(x *RNSPolynomial) *RNSPolynomial {
+	if x.isNTT {
+		return x
+	}
+	a := x.Copy()
+	r.NTT(a)
+	return a
+}
+
+// FromNTT returns the coefficient form of x. It returns x itself if it is
+// already in coefficient form, and a transformed copy otherwise.
+func (r *RNSRing) FromNTT(x *RNSPolynomial) *RNSPolynomial {
+	if !x.isNTT {
+		return x
+	}
+	a := x.Copy()
+	r.INTT(a)
+	return a
+}
+
+// Hadamard returns a polynomial `z` with `z[i][j] = x[i][j] * y[i][j] mod
+// q_i`. If x and y are in evaluation form, then z is their product in
+// evaluation form.
+func (r *RNSRing) Hadamard(x, y *RNSPolynomial) *RNSPolynomial {
+	r.checkLimbs(x, y)
+	z := r.NewPolynomial()
+	z.isNTT = true
+	for i, table := range r.tables {
+		table.hadamard(z.Coeffs[i], x.Coeffs[i], y.Coeffs[i])
+	}
+	return z
+}
+
+// NTT computes the Number-Theoretic Transform of each limb of x. It mutates
+// x with NTT(x) in bit-reversed order (see Multiplier.NTT).
+func (r *RNSRing) NTT(x *RNSPolynomial) {
+	r.checkLimbs(x)
+	x.isNTT = true
+	for i, table := range r.tables {
+		table.forward(x.Coeffs[i])
+	}
+}
+
+// INTT computes the inverse Number-Theoretic Transform of each limb of x, so
+// that INTT(NTT(x)) = x (see Multiplier.INTT).
+func (r *RNSRing) INTT(x *RNSPolynomial) {
+	r.checkLimbs(x)
+	x.isNTT = false
+	for i, table := range r.tables {
+		table.inverse(x.Coeffs[i])
+	}
+}
+
+//
+// Internal functions
+//
+
+// setCRTConstants sets Q and the constants of the CRT reconstruction.
+func (r *RNSRing) setCRTConstants() {
+	r.Q = big.NewInt(1)
+	for _, q := range r.Moduli {
+		r.Q.Mul(r.Q, new(big.Int).SetUint64(q))
+	}
+	r.qHat = make([]*big.Int, len(r.Moduli))
+	r.qHatInv = make([]uint64, len(r.Moduli))
+	for i, q := range r.Moduli {
+		bigQ := new(big.Int).SetUint64(q)
+		r.qHat[i] = new(big.Int).Quo(r.Q, bigQ)
+		qHatMod := new(big.Int).Mod(r.qHat[i], bigQ)
+		r.qHatInv[i] = modularInverse(qHatMod, bigQ).Uint64()
+	}
+	last := len(r.Moduli) - 1
+	r.lastInv = make([]uint64, last)
+	r.lastInvShoup = make([]uint64, last)
+	for i, table := range r.tables[:last] {
+		mod := table.mod
+		bigQ := new(big.Int).SetUint64(mod.Q)
+		r.lastInv[i] = modularInverse(new(big.Int).SetUint64(mod.Reduce(r.Moduli[last])), bigQ).Uint64()
+		r.lastInvShoup[i] = mod.Shoup(r.lastInv[i])
+	}
+}
+
+func (r *RNSRing) checkDomains(x, y *RNSPolynomial) {
+	r.checkLimbs(x, y)
+	if x.isNTT != y.isNTT {
+		panic("RNS polynomials are in different forms")
+	}
+}
+
+func (r *RNSRing) checkLimbs(polys ...*RNSPolynomial) {
+	for _, x := range polys {
+		if len(x.Coeffs) != len(r.Moduli) {
+			panic("RNS polynomial and ring have different number of limbs")
+		}
+		for _, limb := range x.Coeffs {
+			if len(limb) != r.N {
+				panic("RNS polynomial and ring have different dimensions")
+			}
+		}
+	}
+}
@@ -0,0 +1,148 @@
+package negacyclic
+
+import (
+	"math/big"
+)
+
+// nttTable contains the precomputations of the Number-Theoretic Transform
+// modulo a word-sized prime q = 1 mod 2n. The roots are the same ones of a
+// Multiplier modulo q, so that both transforms produce identical outputs.
+//
+// Twiddle factors are stored along with their Shoup precomputations, and the
+// butterflies are Harvey's lazy ones: intermediate values lie in [0, 4q) and
+// are only fully reduced at the end of the transform. This requires 4q < 2^64.
+type nttTable struct {
+	n                  int
+	mod                *Modulus
+	nInvQ, nInvQShoup  uint64
+	rootsBitReverse    []uint64
+	rootsShoup         []uint64
+	invRootsBitReverse []uint64
+	invRootsShoup      []uint64
+}
+
+func newNTTTable(n int, q uint64) *nttTable {
+	bigQ := new(big.Int).SetUint64(q)
+	if !bigQ.ProbablyPrime(32) {
+		panic("NTT table expects prime modulus")
+	}
+	if q%uint64(2*n) != 1 {
+		panic("q != 1 mod 2n")
+	}
+	g := FindPrimitiveRootOfUnity(2*n, bigQ)
+	gInv := modularInverse(g, bigQ)
+	tab := new(nttTable)
+	tab.n = n
+	tab.mod = NewModulus(q)
+	tab.nInvQ = modularInverse(big.NewInt(int64(n)), bigQ).Uint64()
+	tab.nInvQShoup = tab.mod.Shoup(tab.nInvQ)
+	tab.rootsBitReverse = toWords(rootsOfUnityBitReverse(n, g, bigQ))
+	tab.invRootsBitReverse = toWords(rootsOfUnityBitReverse(n, gInv, bigQ))
+	tab.rootsShoup = make([]uint64, n)
+	tab.invRootsShoup = make([]uint64, n)
+	for i := 0; i < n; i++ {
+		tab.rootsShoup[i] = tab.mod.Shoup(tab.rootsBitReverse[i])
+		tab.invRootsShoup[i] = tab.mod.Shoup(tab.invRootsBitReverse[i])
+	}
+	return tab
+}
+
+// forward is the word-sized version of Multiplier.NTT, based on the CT
+// butterfly. It expects the input in [0, q) and outputs values in [0, q).
+func (tab *nttTable) forward(a []uint64) {
+	n := tab.n
+	q := tab.mod.Q
+	twoQ := 2 * q
+	roots := tab.rootsBitReverse
+	rootsShoup := tab.rootsShoup
+
+	var t, j1, j2 int
+	var s, sShoup, u, v uint64
+
+	t = n
+	for m := 1; m < n; m = 2 * m {
+		t /= 2
+		for i := 0; i < m; i++ {
+			j1 = 2 * i * t
+			j2 = j1 + t - 1
+			s, sShoup = roots[m+i], rootsShoup[m+i]
+			for j := j1; j <= j2; j++ {
+				// Harvey's butterfly: a[j], a[j+t] in [0, 4q).
+				u = a[j]
+				if u >= twoQ {
+					u -= twoQ
+				}
+				v = mulShoupLazy(a[j+t], s, sShoup, q)
+				a[j] = u + v
+				a[j+t] = u - v + twoQ
+			}
+		}
+	}
+	for j := 0; j < n; j++ {
+		a[j] = reduceLazy(a[j], q)
+	}
+}
+
+// inverse is the word-sized version of Multiplier.INTT, based on the GS
+// butterfly. It expects the input in [0, q) and outputs values in [0, q).
+func (tab *nttTable) inverse(a []uint64) {
+	n := tab.n
+	q := tab.mod.Q
+	twoQ := 2 * q
+	rootsInv := tab.invRootsBitReverse
+	rootsInvShoup := tab.invRootsShoup
+
+	var t, h, j1, j2 int
+	var s, sShoup, u, v uint64
+
+	t = 1
+	for m := n; m > 1; m /= 2 {
+		j1 = 0
+		h = m / 2
+		for i := 0; i < h; i++ {
+			j2 = j1 + t - 1
+			s, sShoup = rootsInv[h+i], rootsInvShoup[h+i]
+			for j := j1; j <= j2; j++ {
+				// Harvey's butterfly: a[j], a[j+t] in [0, 2q).
+				u = a[j]
+				v = a[j+t]
+				a[j] = u + v
+				if a[j] >= twoQ {
+					a[j] -= twoQ
+				}
+				a[j+t] = mulShoupLazy(u-v+twoQ, s, sShoup, q)
+			}
+			j1 += 2 * t
+		}
+		t *= 2
+	}
+	for j := 0; j < n; j++ {
+		a[j] = tab.mod.MulShoup(a[j], tab.nInvQ, tab.nInvQShoup)
+	}
+}
+
+// hadamard sets `z[j] = x[j] * y[j] mod q`.
+func (tab *nttTable) hadamard(z, x, y []uint64) {
+	for j := range z {
+		z[j] = tab.mod.Mul(x[j], y[j])
+	}
+}
+
+// reduceLazy returns a mod q for a in [0, 4q).
+func reduceLazy(a, q uint64) uint64 {
+	if a >= 2*q {
+		a -= 2 * q
+	}
+	if a >= q {
+		a -= q
+	}
+	return a
+}
+
+func toWords(slice []*big.Int) []uint64 {
+	words := make([]uint64, len(slice))
+	for i := range slice {
+		words[i] = slice[i].Uint64()
+	}
+	return words
+}
@@ -0,0 +1,265 @@
+package negacyclic_test
+
+import (
+	"math/big"
+	"testing"
+
+	"ckks/negacyclic"
+)
+
+func TestRNSRing(t *testing.T) {
+	t.Run("primes", testRNSPrimes)
+	t.Run("conversion_roundtrip", testRNSRoundtrip)
+	t.Run("addition", testRNSAdd)
+	t.Run("multiplication", testRNSMul)
+	t.Run("evaluation_form", testRNSEvaluationForm)
+	t.Run("NTT_matches_multiplier", testRNSNTTMatchesMultiplier)
+	t.Run("prefix", testRNSPrefix)
+	t.Run("division_by_last_modulus", testRNSDivRound)
+	t.Run("drop_last_moduli", testRNSDropLastModuli)
+	t.Run("select", testRNSSelect)
+	t.Run("lift_limb", testRNSLiftLimb)
+}
+
+func testRNSPrimes(t *testing.T) {
+	n := 1 << 10
+	primes := negacyclic.RNSPrimes(60, 2*n, 5)
+	for i, q := range primes {
+		bigQ := new(big.Int).SetUint64(q)
+		if !bigQ.ProbablyPrime(32) {
+			t.Fatal("not prime")
+		}
+		if bigQ.BitLen() != 60 {
+			t.Fatalf("expected 60 bits, got %d", bigQ.BitLen())
+		}
+		if q%uint64(2*n) != 1 {
+			t.Fatal("q != 1 mod 2n")
+		}
+		if i > 0 && primes[i-1] >= q {
+			t.Fatal("primes are not distinct and increasing")
+		}
+	}
+
+	// There are only a few 20-bit primes equal to 1 mod 2^13.
+	if _, err := negacyclic.FindRNSPrimes(20, 1<<13, 41); err == nil {
+		t.Fatal("expected an error on too many primes")
+	}
+	if _, err := negacyclic.FindRNSPrimes(61, 2*n, 1); err == nil {
+		t.Fatal("expected an error on too long primes")
+	}
+}
+
+func testRNSRoundtrip(t *testing.T) {
+	n := 1 << 8
+	r := negacyclic.NewRNSRing(n, negacyclic.RNSPrimes(50, 2*n, 4))
+	x := randomElement(n, r.Q)
+	x.Mod(r.Q)
+	y := r.ToPolynomial(r.FromPolynomial(x))
+	for i := range x.Coeffs {
+		if x.Coeffs[i].Cmp(y.Coeffs[i]) != 0 {
+			t.Fatalf("got %s, want %s", y.Coeffs[i], x.Coeffs[i])
+		}
+	}
+}
+
+func testRNSAdd(t *testing.T) {
+	n := 1 << 8
+	r := negacyclic.NewRNSRing(n, negacyclic.RNSPrimes(60, 2*n, 3))
+	x := randomElement(n, r.Q)
+	y := randomElement(n, r.Q)
+	xRNS, yRNS := r.FromPolynomial(x), r.FromPolynomial(y)
+
+	sum := r.ToPolynomial(r.Add(xRNS, yRNS))
+	diff := r.ToPolynomial(r.Sub(xRNS, yRNS))
+	neg := r.ToPolynomial(r.Neg(xRNS))
+	c := big.NewInt(-123456789)
+	scaled := r.ToPolynomial(r.MulScalar(xRNS, c))
+	wantSum := negacyclic.Add(x, y).Mod(r.Q)
+	wantDiff := negacyclic.Sub(x, y).Mod(r.Q)
+	wantNeg := negacyclic.Sub(negacyclic.NewPolynomial(n), x).Mod(r.Q)
+	wantScaled := x.Copy()
+	wantScaled.Scale(c)
+	wantScaled.Mod(r.Q)
+	for i := 0; i < n; i++ {
+		if sum.Coeffs[i].Cmp(wantSum.Coeffs[i]) != 0 {
+			t.Fatal("incorrect addition")
+		}
+		if diff.Coeffs[i].Cmp(wantDiff.Coeffs[i]) != 0 {
+			t.Fatal("incorrect subtraction")
+		}
+		if neg.Coeffs[i].Cmp(wantNeg.Coeffs[i]) != 0 {
+			t.Fatal("incorrect negation")
+		}
+		if scaled.Coeffs[i].Cmp(wantScaled.Coeffs[i]) != 0 {
+			t.Fatal("incorrect scalar multiplication")
+		}
+	}
+}
+
+func testRNSMul(t *testing.T) {
+	n := 1 << 8
+	r := negacyclic.NewRNSRing(n, negacyclic.RNSPrimes(60, 2*n, 3))
+	x := randomElement(n, r.Q)
+	y := randomElement(n, r.Q)
+	want := negacyclic.Karatsuba(x, y)
+	want.Mod(r.Q)
+	got := r.ToPolynomial(r.Mul(r.FromPolynomial(x), r.FromPolynomial(y)))
+	for i := range got.Coeffs {
+		if got.Coeffs[i].Cmp(want.Coeffs[i]) != 0 {
+			t.Fatal("incorrect result modulo Q")
+		}
+	}
+}
+
+func testRNSEvaluationForm(t *testing.T) {
+	n := 1 << 8
+	r := negacyclic.NewRNSRing(n, negacyclic.RNSPrimes(60, 2*n, 2))
+	x := r.FromPolynomial(randomElement(n, r.Q))
+	y := r.FromPolynomial(randomElement(n, r.Q))
+	want := r.ToPolynomial(r.Mul(x, y))
+	xNTT, yNTT := r.ToNTT(x), r.ToNTT(y)
+	got := r.ToPolynomial(r.FromNTT(r.Add(r.Hadamard(xNTT, yNTT), r.Neg(r.Sub(xNTT, xNTT)))))
+	if got.IsNTT() {
+		t.Fatal("expected a product in coefficient form")
+	}
+	for i := range want.Coeffs {
+		if want.Coeffs[i].Cmp(got.Coeffs[i]) != 0 {
+			t.Fatal("incorrect product in evaluation form")
+		}
+	}
+}
+
+func testRNSNTTMatchesMultiplier(t *testing.T) {
+	n := 1 << 10
+	primes := negacyclic.RNSPrimes(60, 2*n, 1)
+	q := new(big.Int).SetUint64(primes[0])
+	r := negacyclic.NewRNSRing(n, primes)
+	m := negacyclic.NewMultiplier(n, q)
+	x := randomElement(n, q)
+	xRNS := r.FromPolynomial(x)
+	m.NTT(x)
+	r.NTT(xRNS)
+	for i := range x.Coeffs {
+		if x.Coeffs[i].Uint64() != xRNS.Coeffs[0][i] {
+			t.Fatal("RNS and big integer transforms differ")
+		}
+	}
+}
+
+func testRNSPrefix(t *testing.T) {
+	n := 1 << 8
+	r := negacyclic.NewRNSRing(n, negacyclic.RNSPrimes(60, 2*n, 4))
+	prefix := r.Prefix(2)
+	want := new(big.Int).SetUint64(r.Moduli[0])
+	want.Mul(want, new(big.Int).SetUint64(r.Moduli[1]))
+	if prefix.Q.Cmp(want) != 0 || prefix.Limbs() != 2 {
+		t.Fatal("incorrect prefix modulus")
+	}
+	x := randomElement(n, prefix.Q)
+	y := randomElement(n, prefix.Q)
+	got := prefix.ToPolynomial(prefix.Mul(prefix.FromPolynomial(x), prefix.FromPolynomial(y)))
+	wantProd := negacyclic.Karatsuba(x, y).Mod(prefix.Q)
+	for i := range got.Coeffs {
+		if got.Coeffs[i].Cmp(wantProd.Coeffs[i]) != 0 {
+			t.Fatal("incorrect product modulo the prefix")
+		}
+	}
+}
+
+func testRNSDivRound(t *testing.T) {
+	n := 1 << 8
+	r := negacyclic.NewRNSRing(n, negacyclic.RNSPrimes(40, 2*n, 3))
+	prefix := r.Prefix(2)
+	qLast := new(big.Int).SetUint64(r.Moduli[2])
+	x := randomElement(n, r.Q).Mod(r.Q)
+	got := prefix.ToPolynomial(r.DivRoundByLastModulus(r.FromPolynomial(x)))
+	want := x.ScaleNearest(qLast).Mod(prefix.Q)
+	for i := range got.Coeffs {
+		if got.Coeffs[i].Cmp(want.Coeffs[i]) != 0 {
+			t.Fatalf("got %s, want %s", got.Coeffs[i], want.Coeffs[i])
+		}
+	}
+}
+
+func testRNSDropLastModuli(t *testing.T) {
+	n := 1 << 8
+	r := negacyclic.NewRNSRing(n, negacyclic.RNSPrimes(40, 2*n, 3))
+	prefix := r.Prefix(2)
+	x := randomElement(n, r.Q).Mod(r.Q)
+	want := x.Copy().Mod(prefix.Q)
+	// The limbs are dropped in both forms
+	for _, y := range []*negacyclic.RNSPolynomial{r.FromPolynomial(x), r.ToNTT(r.FromPolynomial(x))} {
+		got := prefix.ToPolynomial(prefix.FromNTT(prefix.DropLastModuli(y)))
+		for i := range got.Coeffs {
+			if got.Coeffs[i].Cmp(want.Coeffs[i]) != 0 {
+				t.Fatalf("got %s, want %s", got.Coeffs[i], want.Coeffs[i])
+			}
+		}
+	}
+}
+
+func testRNSSelect(t *testing.T) {
+	n := 1 << 8
+	r := negacyclic.NewRNSRing(n, negacyclic.RNSPrimes(40, 2*n, 4))
+	indices := []int{0, 3}
+	sub := r.Select(indices)
+	want := new(big.Int).SetUint64(r.Moduli[0])
+	want.Mul(want, new(big.Int).SetUint64(r.Moduli[3]))
+	if sub.Q.Cmp(want) != 0 || sub.Limbs() != 2 {
+		t.Fatal("incorrect modulus of the selected ring")
+	}
+	x := randomElement(n, r.Q).Mod(r.Q)
+	y := randomElement(n, r.Q).Mod(r.Q)
+	// Products in evaluation form are computed on the selected residues.
+	xNTT, yNTT := r.ToNTT(r.FromPolynomial(x)), r.ToNTT(r.FromPolynomial(y))
+	got := sub.ToPolynomial(sub.FromNTT(sub.Hadamard(xNTT.Select(indices), yNTT.Select(indices))))
+	wantProd := negacyclic.Karatsuba(x, y).Mod(sub.Q)
+	for i := range got.Coeffs {
+		if got.Coeffs[i].Cmp(wantProd.Coeffs[i]) != 0 {
+			t.Fatal("incorrect product modulo the selected primes")
+		}
+	}
+}
+
+func testRNSLiftLimb(t *testing.T) {
+	n := 1 << 8
+	primes := negacyclic.RNSPrimes(40, 2*n, 4)
+	r := negacyclic.NewRNSRing(n, primes)
+	x := randomElement(n, r.Q).Mod(r.Q)
+	xRNS := r.FromPolynomial(x)
+	q := new(big.Int).SetUint64(primes[1])
+	// The residues modulo q_1 are lifted to (-q_1/2, q_1/2].
+	want := x.Copy().Mod(q)
+	got := r.ToPolynomial(r.LiftLimb(xRNS.Coeffs[1], primes[1]))
+	for i := range got.Coeffs {
+		if got.Coeffs[i].Cmp(want.Coeffs[i]) != 0 {
+			t.Fatalf("got %s, want %s", got.Coeffs[i], want.Coeffs[i])
+		}
+	}
+}
+
+func BenchmarkRNSMultiplication(b *testing.B) {
+	b.Run("big-240bits", benchBigMul240)
+	b.Run("RNS-4x60bits", benchRNSMul240)
+}
+
+func benchBigMul240(b *testing.B) {
+	n := 1 << 12
+	q := negacyclic.RLWEPrime(240, 2*n)
+	m := negacyclic.NewMultiplier(n, q)
+	x := randomElement(n, q)
+	y := randomElement(n, q)
+	for i := 0; i < b.N; i++ {
+		m.Mul(x, y)
+	}
+}
+
+func benchRNSMul240(b *testing.B) {
+	n := 1 << 12
+	r := negacyclic.NewRNSRing(n, negacyclic.RNSPrimes(60, 2*n, 4))
+	x := r.FromPolynomial(randomElement(n, r.Q))
+	y := r.FromPolynomial(randomElement(n, r.Q))
+	for i := 0; i < b.N; i++ {
+		r.Mul(x, y)
+	}
+}
@@ -1,6 +1,7 @@
 package negacyclic_test
 
 import (
+	"math/big"
 	"math/rand"
 	"testing"
 
@@ -12,6 +13,8 @@ func TestDistributions(t *testing.T) {
 	t.Run("HWT", testHWT)
 	t.Run("DG", testDG)
 	t.Run("zeroDG", testZeroDG)
+	t.Run("uniform_from_seed", testUniformModFromSeed)
+	t.Run("uniform_rns_from_seed", testUniformRNSFromSeed)
 }
 
 func testRLWE(t *testing.T) {
@@ -63,3 +66,63 @@ func testZeroDG(t *testing.T) {
 		}
 	}
 }
+
+func testUniformModFromSeed(t *testing.T) {
+	n := 1 << 8
+	q := negacyclic.RLWEPrime(100, n)
+	seed := negacyclic.NewSeed()
+	x := negacyclic.UniformModFromSeed(n, q, seed)
+	y := negacyclic.UniformModFromSeed(n, q, seed)
+	z := negacyclic.UniformModFromSeed(n, q, negacyclic.NewSeed())
+	equal := 0
+	top := new(big.Int).Rsh(q, 1)
+	high := 0
+	for i := range x {
+		if x[i].Sign() < 0 || x[i].Cmp(q) >= 0 {
+			t.Fatalf("coefficient %s out of [0, q)", x[i])
+		}
+		if x[i].Cmp(y[i]) != 0 {
+			t.Fatal("same seed should expand to the same polynomial")
+		}
+		if x[i].Cmp(z[i]) == 0 {
+			equal++
+		}
+		if x[i].Cmp(top) > 0 {
+			high++
+		}
+	}
+	if equal > 0 {
+		t.Fatal("different seeds should expand to different polynomials")
+	}
+	if high < n/4 || high > 3*n/4 {
+		t.Fatalf("%d of %d coefficients above q/2", high, n)
+	}
+}
+
+func testUniformRNSFromSeed(t *testing.T) {
+	n := 1 << 8
+	moduli := negacyclic.RNSPrimes(40, 2*n, 3)
+	seed := negacyclic.NewSeed()
+	x := negacyclic.UniformRNSFromSeed(n, moduli, 2, seed)
+	y := negacyclic.UniformRNSFromSeed(n, moduli, 2, seed)
+	if len(x) != 2 || !x[0].IsNTT() {
+		t.Fatal("expected two polynomials in evaluation form")
+	}
+	for i, q := range moduli {
+		high := 0
+		for j, coeff := range x[0].Coeffs[i] {
+			if coeff >= q {
+				t.Fatalf("residue %d out of [0, q)", coeff)
+			}
+			if coeff != y[0].Coeffs[i][j] || x[1].Coeffs[i][j] != y[1].Coeffs[i][j] {
+				t.Fatal("same seed should expand to the same polynomials")
+			}
+			if coeff > q/2 {
+				high++
+			}
+		}
+		if high < n/4 || high > 3*n/4 {
+			t.Fatalf("%d of %d residues above q/2", high, n)
+		}
+	}
+}
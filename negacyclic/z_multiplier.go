@@ -2,23 +2,48 @@ package negacyclic
 
 import (
 	"math/big"
+	"sync"
 )
 
 // ZMultiplier handles the multiplication in a negacyclic ring of the form
-// Z[X]/(X^n+1). Internally, it chooses a prime larger than the expected
-// coefficients, and multiplies modulo this prime.
+// Z[X]/(X^n+1). Internally, it multiplies modulo a product Q of word-sized
+// primes (see RNSRing), chosen larger than twice the coefficients of the
+// products, and lifts the result to (-Q/2, Q/2].
+//
+// The primes are chosen once, from the bound on the coefficients of the
+// operands declared on creation. Products of operands exceeding this bound are
+// still computed exactly, by growing a second set of primes which is kept for
+// subsequent calls.
 type ZMultiplier struct {
-	N int
+	N     int
+	Bound *big.Int // Declared bound on the coefficients of the operands
+	ring  *RNSRing // Sized for Bound
+
+	mu    sync.Mutex
+	grown *RNSRing // Sized for the largest product seen beyond Bound
 }
 
 // NewZMultiplier creates and returns a ZMultiplier with the given
-// parameters, after proper sanitization.
+// parameters, after proper sanitization. No bound is declared on the
+// operands, so that primes are added as products require them.
 func NewZMultiplier(n int) *ZMultiplier {
+	return NewBoundedZMultiplier(n, big.NewInt(1))
+}
+
+// NewBoundedZMultiplier creates and returns a ZMultiplier for operands whose
+// coefficients are bounded by `bound` in absolute value, after proper
+// sanitization.
+func NewBoundedZMultiplier(n int, bound *big.Int) *ZMultiplier {
 	if !isPowerOfTwo(n) {
 		panic("multiplier expects `n` power of two")
 	}
+	if bound.Sign() <= 0 {
+		panic("multiplier expects a positive bound")
+	}
 	m := new(ZMultiplier)
 	m.N = n
+	m.Bound = new(big.Int).Set(bound)
+	m.ring = newZRing(n, productBound(n, bound, bound))
 	return m
 }
 
@@ -30,16 +55,64 @@ func (m *ZMultiplier) Mul(x, y *Polynomial) *Polynomial {
 	if x.Deg() != m.N {
 		panic("bad multiply length")
 	}
-	prime := big.NewInt(int64(2 * m.N))
-	prime.Mul(prime, normInfinite(x)).Mul(prime, normInfinite(y))
-	prime.Add(prime, big.NewInt(1))
-	for !prime.ProbablyPrime(32) {
-		prime.Add(prime, big.NewInt(int64(2*m.N)))
+	ring := m.ringFor(productBound(m.N, normInfinite(x), normInfinite(y)))
+	return ring.ToPolynomial(ring.Mul(ring.FromPolynomial(x), ring.FromPolynomial(y)))
+}
+
+// ToNTT returns the evaluation form of x, as residues modulo the primes
+// chosen for the declared bound. The coefficients of x must satisfy the bound,
+// so that the result can be multiplied with any other such polynomial (see
+// Hadamard).
+func (m *ZMultiplier) ToNTT(x *Polynomial) *RNSPolynomial {
+	if x.isNTT {
+		panic("ZMultiplier expects polynomials in coefficient form")
+	}
+	if normInfinite(x).Cmp(m.Bound) > 0 {
+		panic("polynomial exceeds the declared bound")
+	}
+	return m.ring.ToNTT(m.ring.FromPolynomial(x))
+}
+
+// Hadamard returns the product of x and y in evaluation form (see ToNTT).
+func (m *ZMultiplier) Hadamard(x, y *RNSPolynomial) *RNSPolynomial {
+	return m.ring.Hadamard(x, y)
+}
+
+// FromNTT returns the polynomial of Z[X]/(X^n+1) whose evaluation form is x.
+func (m *ZMultiplier) FromNTT(x *RNSPolynomial) *Polynomial {
+	return m.ring.ToPolynomial(m.ring.FromNTT(x))
+}
+
+//
+// Internal functions
+//
+
+// ringFor returns a ring whose modulus exceeds twice the given bound on the
+// coefficients of a product.
+func (m *ZMultiplier) ringFor(bound *big.Int) *RNSRing {
+	if bound.Cmp(m.ring.Q) < 0 {
+		return m.ring
+	}
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if m.grown == nil || bound.Cmp(m.grown.Q) >= 0 {
+		m.grown = newZRing(m.N, bound)
 	}
-	modM := NewMultiplier(m.N, prime)
-	pol := modM.Mul(x, y)
-	pol.Mod(prime)
-	return pol
+	return m.grown
+}
+
+// productBound returns 2 * n * xBound * yBound, which is twice a bound on the
+// coefficients of a negacyclic product of dimension n.
+func productBound(n int, xBound, yBound *big.Int) *big.Int {
+	bound := big.NewInt(int64(2 * n))
+	return bound.Mul(bound, xBound).Mul(bound, yBound)
+}
+
+// newZRing returns an RNSRing with modulus Q > bound.
+func newZRing(n int, bound *big.Int) *RNSRing {
+	// Each prime has MaxRNSModulusBitLen bits, i.e. is larger than 2^59.
+	count := bound.BitLen()/(MaxRNSModulusBitLen-1) + 1
+	return NewRNSRing(n, RNSPrimes(MaxRNSModulusBitLen, 2*n, count))
 }
 
 func normInfinite(pol *Polynomial) *big.Int {
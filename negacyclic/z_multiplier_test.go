@@ -0,0 +1,83 @@
+package negacyclic_test
+
+import (
+	"math/big"
+	"testing"
+
+	"ckks/negacyclic"
+)
+
+func TestZMultiplication(t *testing.T) {
+	t.Run("exact_product", testZMulExact)
+	t.Run("growth_beyond_bound", testZMulGrowth)
+	t.Run("evaluation_form", testZMulEvaluationForm)
+}
+
+func testZMulExact(t *testing.T) {
+	n := 1 << 8
+	bound := new(big.Int).Lsh(big.NewInt(1), 200)
+	m := negacyclic.NewBoundedZMultiplier(n, bound)
+	x := randomSignedElement(n, bound)
+	y := randomSignedElement(n, bound)
+	checkExactProduct(t, m.Mul(x, y), x, y)
+}
+
+func testZMulGrowth(t *testing.T) {
+	n := 1 << 8
+	m := negacyclic.NewBoundedZMultiplier(n, big.NewInt(1<<20))
+	for _, bitLen := range []int{10, 300, 100, 500} {
+		bound := new(big.Int).Lsh(big.NewInt(1), uint(bitLen))
+		x := randomSignedElement(n, bound)
+		y := randomSignedElement(n, bound)
+		checkExactProduct(t, m.Mul(x, y), x, y)
+	}
+}
+
+func testZMulEvaluationForm(t *testing.T) {
+	n := 1 << 8
+	bound := new(big.Int).Lsh(big.NewInt(1), 100)
+	m := negacyclic.NewBoundedZMultiplier(n, bound)
+	x := randomSignedElement(n, bound)
+	y := randomSignedElement(n, bound)
+	xNTT, yNTT := m.ToNTT(x), m.ToNTT(y)
+	if !xNTT.IsNTT() {
+		t.Fatal("expected evaluation form")
+	}
+	roundtrip := m.FromNTT(xNTT)
+	for i := range x.Coeffs {
+		if roundtrip.Coeffs[i].Cmp(x.Coeffs[i]) != 0 {
+			t.Fatal("evaluation form roundtrip failed")
+		}
+	}
+	checkExactProduct(t, m.FromNTT(m.Hadamard(xNTT, yNTT)), x, y)
+}
+
+func checkExactProduct(t *testing.T, got, x, y *negacyclic.Polynomial) {
+	want := negacyclic.Karatsuba(x, y)
+	for i := range want.Coeffs {
+		if got.Coeffs[i].Cmp(want.Coeffs[i]) != 0 {
+			t.Fatalf("got %s, want %s", got.Coeffs[i], want.Coeffs[i])
+		}
+	}
+}
+
+// randomSignedElement returns a polynomial with coefficients in [-bound, bound).
+func randomSignedElement(dim int, bound *big.Int) *negacyclic.Polynomial {
+	pol := randomElement(dim, new(big.Int).Lsh(bound, 1))
+	for _, coeff := range pol.Coeffs {
+		coeff.Sub(coeff, bound)
+	}
+	return pol
+}
+
+func BenchmarkZMultiplication(b *testing.B) {
+	n := 1 << 12
+	bound := new(big.Int).Lsh(big.NewInt(1), 200)
+	m := negacyclic.NewBoundedZMultiplier(n, bound)
+	x := randomSignedElement(n, bound)
+	y := randomSignedElement(n, bound)
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		m.Mul(x, y)
+	}
+}
@@ -0,0 +1,117 @@
+package ckks
+
+import (
+	"math"
+	"math/big"
+)
+
+// This file tracks the bounds of the messages and of the noise of ciphertexts,
+// in the canonical embedding norm, as in the article: a ciphertext is a tuple
+// (c, l, ν, B) where ν bounds its message and B its noise. The bounds are
+// updated by the homomorphic operations:
+//
+//   - Encryption: ν is the bound of the plaintext, and B is Bclean (Lemma 1),
+//     or BcleanSk for secret-key encryption.
+//   - Rescaling by a factor r: (ν/r, B/r + bScale) (Lemma 2).
+//   - Addition: (ν1 + ν2, B1 + B2) (Lemma 3).
+//   - Tensor product: (ν1 ν2, ν1 B2 + ν2 B1 + B1 B2) (Lemma 3). Key switching,
+//     for relinearization and automorphisms, adds BMul(q_l).
+//   - Product with a plaintext of bound ν': (ν ν', B ν').
+//
+// The slots of a ciphertext are its message divided by its scale, so that
+// their error is at most B / scale (see Precision).
+
+// MessageBound returns the bound of the canonical embedding norm of the
+// message of the ciphertext, at its scale.
+func (ciph *Ciphertext) MessageBound() *big.Float {
+	return new(big.Float).Copy(ciph.nu)
+}
+
+// NoiseBound returns the bound of the canonical embedding norm of the noise of
+// the ciphertext, at its scale.
+func (ciph *Ciphertext) NoiseBound() *big.Float {
+	return new(big.Float).Copy(ciph.noise)
+}
+
+// Precision returns the estimated remaining precision of the slots of the
+// ciphertext, in bits after the binary point: log2(scale / B), for its noise
+// bound B. It is negative infinity if the bounds of the message and the noise
+// reach q_l / 2, in which case decryption is meaningless.
+func (ciph *Ciphertext) Precision() float64 {
+	total := newBound().Add(ciph.nu, ciph.noise)
+	half := newBound().SetInt(ciph.ql)
+	half.Quo(half, big.NewFloat(2))
+	if total.Cmp(half) >= 0 {
+		return math.Inf(-1)
+	}
+	if ciph.noise.Sign() == 0 {
+		return math.Inf(1)
+	}
+	return log2Float(ciph.scale) - log2Float(ciph.noise)
+}
+
+// bound returns the bound of the canonical embedding norm of the plaintext. It
+// is computed on encoding, and otherwise bounded by the l1 norm of the
+// coefficients.
+func (plt *Plaintext) bound() *big.Float {
+	if plt.nu != nil {
+		return new(big.Float).Copy(plt.nu)
+	}
+	l1 := new(big.Int)
+	abs := new(big.Int)
+	for _, coeff := range plt.m.Coeffs {
+		l1.Add(l1, abs.Abs(coeff))
+	}
+	return newBound().SetInt(l1)
+}
+
+// addBounds sets the bounds of the sum of c1 and c2 to res, and returns it.
+func addBounds(res, c1, c2 *Ciphertext) *Ciphertext {
+	res.nu = newBound().Add(c1.nu, c2.nu)
+	res.noise = newBound().Add(c1.noise, c2.noise)
+	return res
+}
+
+// mulBounds sets the bounds of the tensor product of c1 and c2 to res, and
+// returns it.
+func mulBounds(res, c1, c2 *Ciphertext) *Ciphertext {
+	res.nu = newBound().Mul(c1.nu, c2.nu)
+	res.noise = newBound().Mul(c1.nu, c2.noise)
+	res.noise.Add(res.noise, newBound().Mul(c2.nu, c1.noise))
+	res.noise.Add(res.noise, newBound().Mul(c1.noise, c2.noise))
+	return res
+}
+
+// scaleBounds multiplies the bounds of res by x, and returns it.
+func scaleBounds(res *Ciphertext, x *big.Float) *Ciphertext {
+	res.nu = newBound().Mul(res.nu, x)
+	res.noise = newBound().Mul(res.noise, x)
+	return res
+}
+
+// keySwitchBounds adds the noise of key switching to res, and returns it.
+func (ins *Instance) keySwitchBounds(res *Ciphertext) *Ciphertext {
+	res.noise = newBound().Add(res.noise, newBound().SetInt(ins.BMul(res.ql)))
+	return res
+}
+
+// rescaleBounds divides the bounds of ciph by the factor of a rescaling, and
+// adds its rounding error.
+func (ins *Instance) rescaleBounds(ciph *Ciphertext, factor *big.Int) {
+	r := newBound().SetInt(factor)
+	ciph.nu = newBound().Quo(ciph.nu, r)
+	ciph.noise = newBound().Quo(ciph.noise, r)
+	ciph.noise.Add(ciph.noise, newBound().SetInt(ins.bScale))
+}
+
+func newBound() *big.Float {
+	return new(big.Float).SetPrec(ScalePrecision)
+}
+
+// log2Float returns the base 2 logarithm of x > 0.
+func log2Float(x *big.Float) float64 {
+	mant := new(big.Float)
+	exp := x.MantExp(mant)
+	f, _ := mant.Float64()
+	return float64(exp) + math.Log2(f)
+}
@@ -1,17 +1,45 @@
 package ckks
 
 import (
+	"math/big"
 	"strconv"
+
+	"ckks/negacyclic"
 )
 
 // Parameters of the CKKS scheme.
 type Parameters struct {
-	N       int // Dimension of the cyclotomic ring; must be a power of two
-	Depth   int // Maximum allowed homomorphic depth
-	BitLenP int
-	BitLenQ int     // base p > 0 for scaling
-	Hamming int     // Hamming weight of secret vector
-	Sigma   float64 // Std. deviation for discrete Gaussians
+	N       int     `json:"n"`     // Dimension of the cyclotomic ring; must be a power of two
+	Depth   int     `json:"depth"` // Maximum allowed homomorphic depth
+	BitLenP int     `json:"bitLenP"`
+	BitLenQ int     `json:"bitLenQ"` // base p > 0 for scaling
+	Hamming int     `json:"hamming"` // Hamming weight of secret vector
+	Sigma   float64 `json:"sigma"`   // Std. deviation for discrete Gaussians
+	RNS     bool    `json:"rns"`     // RNS variant, for a chain q_0 * q_1 * ... * q_L (see rns.go)
+
+	// Bit security under which NewInstance returns ErrWarningInsecure, or zero
+	// for DefaultMinimumSecurity. It is a local policy, which is neither
+	// serialized nor part of the fingerprint of an instance.
+	MinSecurity float64 `json:"-"`
+}
+
+// Moduli are the moduli of an instance, resolved from the bit lengths of its
+// parameters (see NewInstance), or given explicitly (see
+// NewInstanceWithModuli).
+type Moduli struct {
+	P   *big.Int `json:"p"`             // Scaling prime; q_1 in RNS mode
+	Q0  *big.Int `json:"q0"`            // Modulus of the last level
+	PEv *big.Int `json:"pEv"`           // Key switching modulus P; a word-sized prime in RNS mode
+	RNS []uint64 `json:"rns,omitempty"` // q_0, ..., q_L, in RNS mode
+}
+
+// ParameterSet describes an instance exactly: a server can publish it, so that
+// clients reconstruct an identical instance (see NewInstanceFromParameterSet).
+// It serializes to JSON with the encoding/json package, and to binary with
+// MarshalBinary.
+type ParameterSet struct {
+	Parameters Parameters `json:"parameters"`
+	Moduli     Moduli     `json:"moduli"`
 }
 
 func (pars *Parameters) String() string {
@@ -22,5 +50,115 @@ func (pars *Parameters) String() string {
 	str += "  BitLen(q): " + strconv.Itoa(pars.BitLenQ) + "\n"
 	str += "  Hamming (secret key): " + strconv.Itoa(pars.Hamming) + "\n"
 	str += "  Std.Dev (Gaussian sampling): " + sigma + "\n"
+	str += "  RNS: " + strconv.FormatBool(pars.RNS) + "\n"
 	return str
 }
+
+// Copy returns a deep copy of the moduli.
+func (m *Moduli) Copy() *Moduli {
+	res := &Moduli{P: copyInt(m.P), Q0: copyInt(m.Q0), PEv: copyInt(m.PEv)}
+	if m.RNS != nil {
+		res.RNS = append([]uint64(nil), m.RNS...)
+	}
+	return res
+}
+
+// maxDepth bounds the depth of instances built from explicit moduli, so that
+// published parameters cannot trigger the precomputation of huge chains.
+const maxDepth = 256
+
+// check returns an ErrBadParameters error unless the moduli are primes of the
+// bit lengths given by the parameters, P is as large as q_L (as derived by
+// NewInstance) but small enough for keys to be decoded, and the primes of the
+// RNS chain are distinct, of at most MaxRNSModulusBitLen bits, and 1 mod 2N.
+// In RNS mode, P is such a prime as well, distinct from the chain, and as
+// large as its primes.
+func (m *Moduli) check(params *Parameters) error {
+	if params.N < 2 || params.N&(params.N-1) != 0 {
+		return ErrBadParameters("ring dimension should be a power of 2")
+	}
+	if params.Depth < 0 || params.Depth > maxDepth {
+		return ErrBadParameters("depth should lie in [0, 256]")
+	}
+	if params.BitLenP <= 0 || params.BitLenQ <= 0 {
+		return ErrBadParameters("bit lengths should be positive")
+	}
+	if m.P == nil || m.Q0 == nil || m.PEv == nil {
+		return ErrBadParameters("missing modulus")
+	}
+	for _, q := range []*big.Int{m.P, m.Q0, m.PEv} {
+		if q.Sign() <= 0 || !q.ProbablyPrime(32) {
+			return ErrBadParameters("moduli should be primes")
+		}
+	}
+	if m.Q0.BitLen() != params.BitLenQ {
+		return ErrBadParameters("q0 should have BitLenQ bits")
+	}
+	if !params.RNS {
+		// Key switching divides by P products with polynomials modulo q_L.
+		if m.PEv.BitLen() < params.BitLenQ+params.Depth*params.BitLenP {
+			return ErrBadParameters("P should have at least BitLenQ + Depth * BitLenP bits")
+		}
+		if m.RNS != nil {
+			return ErrBadParameters("RNS primes given for a classic chain")
+		}
+		if m.P.BitLen() != params.BitLenP {
+			return ErrBadParameters("p should have BitLenP bits")
+		}
+		if m.P.Cmp(m.Q0) == 0 || m.PEv.Cmp(m.P) == 0 || m.PEv.Cmp(m.Q0) == 0 {
+			return ErrBadParameters("moduli should be distinct")
+		}
+		qL := new(big.Int).Exp(m.P, big.NewInt(int64(params.Depth)), nil)
+		return m.checkKeyBound(qL.Mul(qL, m.Q0))
+	}
+	if len(m.RNS) != params.Depth+1 {
+		return ErrBadParameters("the RNS chain should have Depth + 1 primes")
+	}
+	if m.RNS[0] != m.Q0.Uint64() || !m.Q0.IsUint64() {
+		return ErrBadParameters("the RNS chain should start with q0")
+	}
+	if params.Depth > 0 && m.P.Cmp(new(big.Int).SetUint64(m.RNS[1])) != 0 {
+		return ErrBadParameters("p should be q_1 in RNS mode")
+	}
+	twoN := uint64(2 * params.N)
+	// Key switching divides by P products with the residues modulo each prime.
+	if m.PEv.BitLen() > negacyclic.MaxRNSModulusBitLen || m.PEv.Uint64()%twoN != 1 ||
+		m.PEv.BitLen() < params.BitLenP || m.PEv.BitLen() < params.BitLenQ {
+		return ErrBadParameters("P should be a prime of at most 60 bits, equal to 1 mod 2N, and of at least BitLenP and BitLenQ bits")
+	}
+	seen := make(map[uint64]bool)
+	for l, q := range m.RNS {
+		bigQ := new(big.Int).SetUint64(q)
+		if seen[q] || bigQ.Cmp(m.PEv) == 0 {
+			return ErrBadParameters("moduli should be distinct")
+		}
+		seen[q] = true
+		if bigQ.BitLen() > negacyclic.MaxRNSModulusBitLen || q%twoN != 1 || !bigQ.ProbablyPrime(32) {
+			return ErrBadParameters("RNS moduli should be primes of at most 60 bits, equal to 1 mod 2N")
+		}
+		if l > 0 && bigQ.BitLen() != params.BitLenP {
+			return ErrBadParameters("q_1, ..., q_L should have BitLenP bits")
+		}
+	}
+	qL := big.NewInt(1)
+	for _, q := range m.RNS {
+		qL.Mul(qL, new(big.Int).SetUint64(q))
+	}
+	return m.checkKeyBound(qL)
+}
+
+// checkKeyBound returns an ErrBadParameters error unless keys modulo qL, in
+// evaluation form with respect to q_L * P, can be decoded (see keyBoundFits).
+func (m *Moduli) checkKeyBound(qL *big.Int) error {
+	if !keyBoundFits(qL, new(big.Int).Mul(qL, m.PEv)) {
+		return ErrBadParameters("P should have at most 256 bits more than q_L")
+	}
+	return nil
+}
+
+func copyInt(x *big.Int) *big.Int {
+	if x == nil {
+		return nil
+	}
+	return new(big.Int).Set(x)
+}
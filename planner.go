@@ -0,0 +1,204 @@
+package ckks
+
+import (
+	"fmt"
+	"math"
+	"math/bits"
+	"strings"
+)
+
+// This file plans parameters for a computation, from the noise bounds of the
+// article: Bclean for fresh ciphertexts (Lemma 1), bScale for rescaling
+// (Lemma 2), and BMul for multiplication (Lemma 3). The planned circuit is a
+// chain of Depth multiplications, each followed by a rescaling by p, at the
+// scale Δ = p. For inputs bounded by M, the messages after l multiplications
+// are bounded by M^(2^l) Δ, and the noise of a product of ciphertexts with
+// messages ν and noise B is
+//
+//	(2 ν B + B^2 + BMul(q_l)) / p + bScale.
+//
+// The output precision is the number of bits of the slots after the binary
+// point, log2(Δ / B) for the noise B of the output. The planner chooses the
+// smallest dimension N, and for it the smallest p, achieving the precision,
+// then q_0 large enough for the output, and checks the security of the
+// resulting modulus P * q_L (see MaxModulusBitLen and EstimateSecurity),
+// first with sparse secrets, then with dense ones.
+
+// Error distribution of the planned parameters, and sparse Hamming weight of
+// their secrets, as in the article. Dense secrets, of Hamming weight N/2, are
+// planned when sparse ones are not secure enough.
+const (
+	planSigma   = 3.2
+	planHamming = 64
+
+	maxPlanBitLenP = 120
+)
+
+// PlanRequest describes the computation to plan parameters for.
+type PlanRequest struct {
+	Depth        int     // Multiplicative depth
+	Precision    int     // Bits of precision of the outputs, after the binary point
+	MaxMagnitude float64 // Bound of the absolute values of the inputs
+	Security     int     // Target bit security: 128, 192 or 256
+}
+
+// Plan contains the planned parameters, and the noise budget of the planned
+// computation.
+type Plan struct {
+	Parameters    Parameters
+	Levels        []PlanLevel // From level Depth, after encryption, down to 0
+	Precision     float64     // Estimated bits of precision of the outputs
+	ModulusBitLen int         // Bit length of P * q_L
+	MaxBitLen     int         // Maximal bit length allowed by the standard
+	Security      float64     // Estimated bit security (see EstimateSecurity)
+}
+
+// PlanLevel is the noise budget of a ciphertext of the planned computation.
+type PlanLevel struct {
+	Level       int
+	ModulusBits int     // Bit length of q_l
+	MessageBits float64 // log2 of the bound of the scaled messages
+	NoiseBits   float64 // log2 of the bound of the noise
+	BMulBits    float64 // log2 of BMul(q_l) for the product leading here; 0 after encryption
+}
+
+// PlanParameters returns parameters for the computation described by req,
+// with the smallest dimension achieving its precision and security. It
+// returns an ErrBadParameters error if the request is invalid, and
+// ErrNoParameters if no dimension up to 2^15 is large enough.
+func PlanParameters(req *PlanRequest) (*Plan, error) {
+	if req.Depth < 0 {
+		return nil, ErrBadParameters("depth should be non-negative")
+	}
+	if req.Precision <= 0 {
+		return nil, ErrBadParameters("precision should be positive")
+	}
+	if !(req.MaxMagnitude > 0) || math.IsInf(req.MaxMagnitude, 0) {
+		return nil, ErrBadParameters("maximum magnitude should be positive and finite")
+	}
+	if MaxModulusBitLen(req.Security, 1<<15) == 0 {
+		return nil, ErrBadParameters("security should be 128, 192 or 256")
+	}
+	for logN := 10; logN <= 15; logN++ {
+		n := 1 << uint(logN)
+		for _, hamming := range []int{planHamming, n / 2} {
+			plan := planDimension(req, n, hamming)
+			if plan == nil {
+				continue
+			}
+			if plan.ModulusBitLen <= plan.MaxBitLen && plan.Security >= float64(req.Security) {
+				return plan, nil
+			}
+		}
+	}
+	return nil, fmt.Errorf("%w: depth %d, %d bits of precision, %d bits of security",
+		ErrNoParameters, req.Depth, req.Precision, req.Security)
+}
+
+// planDimension returns the plan of the smallest p achieving the precision in
+// dimension n, for secrets of the given Hamming weight, or nil if there is
+// none.
+func planDimension(req *PlanRequest, n, hamming int) *Plan {
+	// Primes of the chain are 1 mod 2N.
+	minBitLen := bits.Len(uint(2*n)) + 1
+	for bitLenP := minBitLen; bitLenP <= maxPlanBitLenP; bitLenP++ {
+		params := Parameters{
+			N:       n,
+			Depth:   req.Depth,
+			BitLenP: bitLenP,
+			Hamming: hamming,
+			Sigma:   planSigma,
+		}
+		levels := noiseBudget(&params, req.MaxMagnitude)
+		last := levels[len(levels)-1]
+		// The scale p may be as small as 2^(bitLenP-1).
+		if float64(bitLenP-1)-last.NoiseBits < float64(req.Precision) {
+			continue
+		}
+		// q_0 holds the messages and the noise of the outputs, with a sign.
+		params.BitLenQ = int(math.Ceil(log2Sum(last.MessageBits, last.NoiseBits))) + 2
+		if params.BitLenQ < minBitLen {
+			params.BitLenQ = minBitLen
+		}
+		levels = noiseBudget(&params, req.MaxMagnitude)
+		last = levels[len(levels)-1]
+		modulusBitLen := 2 * (params.BitLenQ + params.Depth*params.BitLenP)
+		return &Plan{
+			Parameters:    params,
+			Levels:        levels,
+			Precision:     float64(bitLenP-1) - last.NoiseBits,
+			ModulusBitLen: modulusBitLen,
+			MaxBitLen:     MaxModulusBitLen(req.Security, n),
+			Security:      EstimateSecurity(n, float64(modulusBitLen), errorStdDev(planSigma), hamming).Level(),
+		}
+	}
+	return nil
+}
+
+// noiseBudget returns the bounds of the messages and the noise at each level
+// of the planned computation, for inputs bounded by maxMagnitude. BitLenQ may
+// be zero, in which case the moduli are approximated by p^l.
+func noiseBudget(params *Parameters, maxMagnitude float64) []PlanLevel {
+	// Messages are bounded with p < 2^BitLenP, and the noise divided by
+	// p >= 2^(BitLenP-1).
+	logP := float64(params.BitLenP)
+	minLogP := logP - 1
+	logPEv := float64(params.BitLenQ + params.Depth*params.BitLenP)
+	logBks := log2Int(computeBks(params.Sigma, params.N).Int64())
+	logBScale := log2Int(computeBscale(params.N, params.Hamming).Int64())
+
+	level := PlanLevel{
+		Level:       params.Depth,
+		ModulusBits: params.BitLenQ + params.Depth*params.BitLenP,
+		MessageBits: math.Log2(maxMagnitude) + logP,
+		NoiseBits:   log2Int(computeBclean(params.Sigma, params.N, params.Hamming).Int64()),
+	}
+	levels := []PlanLevel{level}
+	for l := params.Depth; l > 0; l-- {
+		// Product at level l, see Lemma 3.
+		bMul := log2Sum(logBks+float64(level.ModulusBits)-logPEv, logBScale)
+		noise := log2Sum(log2Sum(1+level.MessageBits+level.NoiseBits, 2*level.NoiseBits), bMul)
+		// Rescaling by p, see Lemma 2.
+		level = PlanLevel{
+			Level:       l - 1,
+			ModulusBits: level.ModulusBits - params.BitLenP,
+			MessageBits: 2*level.MessageBits - logP,
+			NoiseBits:   log2Sum(noise-minLogP, logBScale),
+			BMulBits:    bMul,
+		}
+		levels = append(levels, level)
+	}
+	return levels
+}
+
+// String explains the plan: its parameters, the noise budget at each level,
+// and its precision and security.
+func (plan *Plan) String() string {
+	var b strings.Builder
+	b.WriteString(plan.Parameters.String())
+	for _, level := range plan.Levels {
+		fmt.Fprintf(&b, "  Level %d (q of %d bits): message 2^%.1f, noise 2^%.1f",
+			level.Level, level.ModulusBits, level.MessageBits, level.NoiseBits)
+		if level.Level == plan.Parameters.Depth {
+			b.WriteString(" (Bclean)\n")
+		} else {
+			fmt.Fprintf(&b, " (BMul 2^%.1f, then rescaling)\n", level.BMulBits)
+		}
+	}
+	fmt.Fprintf(&b, "  Output precision: %.1f bits\n", plan.Precision)
+	fmt.Fprintf(&b, "  Modulus P * q_L: %d bits (at most %d)\n", plan.ModulusBitLen, plan.MaxBitLen)
+	fmt.Fprintf(&b, "  Security: %.1f bits\n", plan.Security)
+	return b.String()
+}
+
+// log2Sum returns log2(2^a + 2^b).
+func log2Sum(a, b float64) float64 {
+	if a < b {
+		a, b = b, a
+	}
+	return a + math.Log2(1+math.Exp2(b-a))
+}
+
+func log2Int(x int64) float64 {
+	return math.Log2(float64(x))
+}
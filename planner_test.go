@@ -0,0 +1,84 @@
+package ckks_test
+
+import (
+	"errors"
+	"math"
+	"math/cmplx"
+	"math/rand"
+	"strings"
+	"testing"
+
+	"ckks"
+)
+
+func testPlanner(t *testing.T) {
+	req := &ckks.PlanRequest{Depth: 1, Precision: 20, MaxMagnitude: 16, Security: 128}
+	plan, err := ckks.PlanParameters(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if plan.Precision < 20 || plan.Security < 128 || plan.ModulusBitLen > plan.MaxBitLen {
+		t.Fatalf("plan does not meet the request:\n%s", plan)
+	}
+	if len(plan.Levels) != 2 || !strings.Contains(plan.String(), "Output precision") {
+		t.Fatalf("unexpected explanation:\n%s", plan)
+	}
+
+	inst, err := ckks.NewInstance(&plan.Parameters)
+	if err != nil {
+		t.Fatal(err)
+	}
+	key := inst.GenerateKey()
+	z := make([]complex128, inst.N/2)
+	want := make([]complex128, inst.N/2)
+	bound := req.MaxMagnitude / math.Sqrt2
+	for i := range z {
+		z[i] = complex((2*rand.Float64()-1)*bound, (2*rand.Float64()-1)*bound)
+		want[i] = z[i] * z[i]
+	}
+	plt, err := inst.Encode(z, inst.GetP())
+	if err != nil {
+		t.Fatal(err)
+	}
+	ciph := inst.Encrypt(key.Public, plt)
+	prod, err := inst.MulAndRescale(key.Evaluation, ciph, ciph)
+	if err != nil {
+		t.Fatal(err)
+	}
+	got := inst.DecodeComplex(inst.Decrypt(key.Secret, prod))
+	for i := range want {
+		if cmplx.Abs(got[i]-want[i]) > math.Exp2(-float64(req.Precision)) {
+			t.Fatalf("slot %d: got %v, want %v", i, got[i], want[i])
+		}
+	}
+
+	// More precision, or a deeper circuit, costs larger moduli.
+	more, err := ckks.PlanParameters(&ckks.PlanRequest{Depth: 1, Precision: 30, MaxMagnitude: 16, Security: 128})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if more.Parameters.BitLenP <= plan.Parameters.BitLenP {
+		t.Fatal("more precision should need a larger scale")
+	}
+	deeper, err := ckks.PlanParameters(&ckks.PlanRequest{Depth: 3, Precision: 20, MaxMagnitude: 16, Security: 128})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if deeper.ModulusBitLen <= plan.ModulusBitLen {
+		t.Fatal("a deeper circuit should need a larger modulus")
+	}
+
+	if _, err := ckks.PlanParameters(&ckks.PlanRequest{Depth: 20, Precision: 40, MaxMagnitude: 16, Security: 256}); !errors.Is(err, ckks.ErrNoParameters) {
+		t.Fatalf("expected ErrNoParameters, got %v", err)
+	}
+	for _, bad := range []ckks.PlanRequest{
+		{Depth: -1, Precision: 20, MaxMagnitude: 1, Security: 128},
+		{Depth: 1, Precision: 0, MaxMagnitude: 1, Security: 128},
+		{Depth: 1, Precision: 20, MaxMagnitude: 0, Security: 128},
+		{Depth: 1, Precision: 20, MaxMagnitude: 1, Security: 100},
+	} {
+		if _, err := ckks.PlanParameters(&bad); err == nil {
+			t.Fatalf("expected an error for %+v", bad)
+		}
+	}
+}
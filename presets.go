@@ -0,0 +1,124 @@
+package ckks
+
+import "fmt"
+
+// This file contains a catalog of named parameter sets, so that applications
+// select parameters by name, or by security and depth, instead of choosing
+// bit lengths by hand.
+//
+// The secure presets follow the tables of the homomorphic encryption standard
+// (Albrecht et al., "Homomorphic Encryption Security Standard", 2018): the
+// largest modulus of an instance, P * q_L, has about 2 (BitLenQ + Depth BitLenP)
+// bits (see NewInstance), which is below the maximal modulus size of the
+// standard for the ring dimension and the target security (see
+// MaxModulusBitLen). Errors have the standard deviation 3.2 of the standard,
+// i.e. Sigma = 3.2^2, since Sigma is a variance (see errorStdDev). Secrets have
+// the Hamming weight N/2, sparser than the uniform ternary secrets of the
+// standard, so that the security estimate of each preset (see
+// EstimateSecurity) is checked against its target as well. The scale is
+// p = 2^40, and q_0 keeps 20 bits for the magnitude of the messages.
+//
+// The test presets are small and fast, but insecure: NewInstance returns them
+// with ErrWarningInsecure.
+
+// Preset is a named set of parameters.
+type Preset struct {
+	Name       string
+	Security   int // Target bit security; 0 for test-only presets
+	Parameters Parameters
+}
+
+// Insecure reports whether the preset is only meant for tests.
+func (pre *Preset) Insecure() bool {
+	return pre.Security == 0
+}
+
+var presets = []Preset{
+	{"sec128-depth1", 128, secureParameters(1<<13, 1)},
+	{"sec128-depth2", 128, secureParameters(1<<14, 2)},
+	{"sec128-depth4", 128, secureParameters(1<<15, 4)},
+	{"sec128-depth8", 128, secureParameters(1<<15, 8)},
+	{"sec192-depth1", 192, secureParameters(1<<14, 1)},
+	{"sec192-depth2", 192, secureParameters(1<<14, 2)},
+	{"sec192-depth4", 192, secureParameters(1<<15, 4)},
+	{"sec256-depth1", 256, secureParameters(1<<14, 1)},
+	{"sec256-depth2", 256, secureParameters(1<<15, 2)},
+	{"sec256-depth4", 256, secureParameters(1<<15, 4)},
+	{"test-depth1", 0, Parameters{N: 1 << 10, Depth: 1, BitLenP: 30, BitLenQ: 60, Hamming: 64, Sigma: 3.2 * 3.2}},
+	{"test-depth2", 0, Parameters{N: 1 << 10, Depth: 2, BitLenP: 30, BitLenQ: 60, Hamming: 64, Sigma: 3.2 * 3.2}},
+	{"test-rns", 0, Parameters{N: 1 << 10, Depth: 2, BitLenP: 30, BitLenQ: 60, Hamming: 64, Sigma: 3.2 * 3.2, RNS: true}},
+}
+
+func secureParameters(n, depth int) Parameters {
+	return Parameters{
+		N:       n,
+		Depth:   depth,
+		BitLenP: 40,
+		BitLenQ: 60,
+		Hamming: n / 2,
+		Sigma:   3.2 * 3.2,
+	}
+}
+
+// heStandard is the table of the maximal bit lengths of the modulus, for
+// uniform ternary secrets and errors of standard deviation 3.2, indexed by the
+// bit security and by log2 N.
+var heStandard = map[int]map[int]int{
+	128: {10: 27, 11: 54, 12: 109, 13: 218, 14: 438, 15: 881},
+	192: {10: 19, 11: 37, 12: 75, 13: 152, 14: 305, 15: 611},
+	256: {10: 14, 11: 29, 12: 58, 13: 118, 14: 237, 15: 476},
+}
+
+// MaxModulusBitLen returns the maximal bit length of the modulus of RLWE in
+// dimension n for the given bit security (128, 192 or 256), according to the
+// homomorphic encryption standard. It returns 0 if the standard does not
+// cover the security or the dimension.
+func MaxModulusBitLen(security, n int) int {
+	logN := 0
+	for 1<<uint(logN) < n {
+		logN++
+	}
+	if 1<<uint(logN) != n {
+		return 0
+	}
+	return heStandard[security][logN]
+}
+
+// Presets returns the catalog of presets.
+func Presets() []Preset {
+	return append([]Preset(nil), presets...)
+}
+
+// PresetByName returns the preset with the given name. It returns an
+// ErrUnknownPreset error if there is none.
+func PresetByName(name string) (*Preset, error) {
+	for i := range presets {
+		if presets[i].Name == name {
+			pre := presets[i]
+			return &pre, nil
+		}
+	}
+	return nil, fmt.Errorf("%w: %q", ErrUnknownPreset, name)
+}
+
+// PresetFor returns the cheapest secure preset of at least the given bit
+// security and depth, i.e. the one with the smallest dimension, then the
+// smallest depth. It returns an ErrUnknownPreset error if there is none.
+func PresetFor(security, depth int) (*Preset, error) {
+	var best *Preset
+	for i := range presets {
+		pre := &presets[i]
+		if pre.Insecure() || pre.Security < security || pre.Parameters.Depth < depth {
+			continue
+		}
+		if best == nil || pre.Parameters.N < best.Parameters.N ||
+			pre.Parameters.N == best.Parameters.N && pre.Parameters.Depth < best.Parameters.Depth {
+			best = pre
+		}
+	}
+	if best == nil {
+		return nil, fmt.Errorf("%w: no preset of %d bits of security and depth %d", ErrUnknownPreset, security, depth)
+	}
+	pre := *best
+	return &pre, nil
+}
@@ -0,0 +1,73 @@
+package ckks_test
+
+import (
+	"errors"
+	"testing"
+
+	"ckks"
+)
+
+func testPresets(t *testing.T) {
+	names := make(map[string]bool)
+	for _, pre := range ckks.Presets() {
+		if names[pre.Name] {
+			t.Fatalf("duplicate preset %s", pre.Name)
+		}
+		names[pre.Name] = true
+		got, err := ckks.PresetByName(pre.Name)
+		if err != nil || got.Parameters != pre.Parameters {
+			t.Fatalf("%s: PresetByName returned %v, %v", pre.Name, got, err)
+		}
+		if pre.Insecure() {
+			continue
+		}
+		// The modulus of the switching keys, P * q_L.
+		params := pre.Parameters
+		bitLen := 2 * (params.BitLenQ + params.Depth*params.BitLenP)
+		if max := ckks.MaxModulusBitLen(pre.Security, params.N); bitLen > max {
+			t.Errorf("%s: modulus of %d bits, the standard allows %d", pre.Name, bitLen, max)
+		}
+	}
+
+	for _, pre := range ckks.Presets() {
+		pre := pre
+		t.Run(pre.Name, func(t *testing.T) {
+			inst, err := ckks.NewInstance(&pre.Parameters)
+			if pre.Insecure() {
+				if err == nil {
+					err = inst.Sanitize()
+				}
+				if err != ckks.ErrWarningInsecure {
+					t.Fatalf("expected a warning on a test preset, got %v", err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if inst.SecurityLevel() < float64(pre.Security) {
+				t.Fatalf("estimated security of %.1f bits, want %d", inst.SecurityLevel(), pre.Security)
+			}
+		})
+	}
+
+	pre, err := ckks.PresetByName("sec128-depth1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	pre.Parameters.N = 0 // Presets are returned by copy.
+	if again, _ := ckks.PresetByName("sec128-depth1"); again.Parameters.N == 0 {
+		t.Fatal("the catalog should not be modified")
+	}
+
+	pre, err = ckks.PresetFor(128, 3)
+	if err != nil || pre.Name != "sec128-depth4" {
+		t.Fatalf("PresetFor(128, 3) returned %v, %v", pre, err)
+	}
+	if _, err := ckks.PresetFor(256, 8); !errors.Is(err, ckks.ErrUnknownPreset) {
+		t.Fatalf("expected ErrUnknownPreset, got %v", err)
+	}
+	if _, err := ckks.PresetByName("nonexistent"); !errors.Is(err, ckks.ErrUnknownPreset) {
+		t.Fatalf("expected ErrUnknownPreset, got %v", err)
+	}
+}
@@ -0,0 +1,385 @@
+package ckks
+
+import (
+	"fmt"
+	"math/big"
+	"strconv"
+	"strings"
+
+	"ckks/negacyclic"
+)
+
+// This file implements the RNS variant of CKKS (see Parameters.RNS). The
+// modulus chain is q_0 * q_1 * ... * q_L, for distinct word-sized primes, so
+// that ciphertexts are stored as their residues modulo q_0, ..., q_l,
+// additions and tensor products are computed limb by limb, and rescaling
+// divides by the last primes of the chain.
+//
+// Keys are switched limb by limb as well, with a word-sized special prime P
+// distinct from the chain. A switching key from t to s is a key (b'_i, a'_i)
+// modulo q_0 * ... * q_L * P for each prime q_i of the chain, with
+//
+//	b'_i = -a'_i s + e'_i + P Q_i t,
+//
+// where Q_i is 1 modulo q_i and 0 modulo the other primes. To switch the key
+// of a polynomial d of level l, each residue [d]_{q_i}, lifted to
+// (-q_i/2, q_i/2], is extended to the primes q_0, ..., q_l, P (basis
+// extension), the products with (b'_i, a'_i) are summed for i <= l, so that
+// the sum is P d t modulo q_0 * ... * q_l * P up to the small a s and noise,
+// and the result is divided by P, dropping its limb (ModDown). Since the
+// digits [d]_{q_i} are bounded by q_i/2, P only needs to be as large as the
+// primes of the chain, and the noise of key switching is bKs * (q_0 + ... +
+// q_l) / P (see BMul). Public keys are those of the classic chain, and
+// encryption reduces fresh ciphertexts modulo each prime.
+
+// rnsPrimes returns the primes q_0, ..., q_L of the RNS chain: q_1, ..., q_L
+// have bitLenP bits, and are distinct from q0. It returns an ErrBadParameters
+// error if there are not enough primes of bitLenP bits equal to 1 mod 2N.
+func rnsPrimes(n, depth, bitLenP int, q0 *big.Int) ([]uint64, error) {
+	candidates, err := negacyclic.FindRNSPrimes(bitLenP, 2*n, depth+1)
+	if err != nil {
+		return nil, ErrBadParameters("not enough primes of BitLenP bits, equal to 1 mod 2N, for the RNS chain")
+	}
+	primes := []uint64{q0.Uint64()}
+	for _, q := range candidates {
+		if len(primes) == depth+1 {
+			break
+		}
+		if q != primes[0] {
+			primes = append(primes, q)
+		}
+	}
+	return primes, nil
+}
+
+// rnsSpecialPrime returns the special prime P of key switching, the first
+// prime of max(BitLenP, BitLenQ) bits equal to 1 mod 2N which is not in the
+// chain, so that P is at least as large as its primes.
+func rnsSpecialPrime(params *Parameters, primes []uint64) (*big.Int, error) {
+	bitLen := params.BitLenP
+	if params.BitLenQ > bitLen {
+		bitLen = params.BitLenQ
+	}
+	candidates, err := negacyclic.FindRNSPrimes(bitLen, 2*params.N, len(primes)+1)
+	if err == nil {
+		chain := make(map[uint64]bool)
+		for _, q := range primes {
+			chain[q] = true
+		}
+		for _, q := range candidates {
+			if !chain[q] {
+				return new(big.Int).SetUint64(q), nil
+			}
+		}
+	}
+	return nil, ErrBadParameters("not enough primes, equal to 1 mod 2N, for the special prime P")
+}
+
+// newRNSChain returns the rings modulo q_0 * ... * q_l, for each level l.
+func newRNSChain(n int, primes []uint64) []*negacyclic.RNSRing {
+	depth := len(primes) - 1
+	top := negacyclic.NewRNSRing(n, primes)
+	rings := make([]*negacyclic.RNSRing, depth+1)
+	for l := 0; l < depth; l++ {
+		rings[l] = top.Prefix(l + 1)
+	}
+	rings[depth] = top
+	return rings
+}
+
+// newKeyRings returns the rings modulo q_0 * ... * q_l * P, for each level l,
+// in which keys are switched.
+func newKeyRings(n int, primes []uint64, pEv uint64) []*negacyclic.RNSRing {
+	depth := len(primes) - 1
+	top := negacyclic.NewRNSRing(n, append(append([]uint64(nil), primes...), pEv))
+	rings := make([]*negacyclic.RNSRing, depth+1)
+	for l := 0; l < depth; l++ {
+		rings[l] = top.Select(keyIndices(l, depth))
+	}
+	rings[depth] = top
+	return rings
+}
+
+// keyIndices returns the indices of q_0, ..., q_level and P among the primes
+// of the keys.
+func keyIndices(level, depth int) []int {
+	indices := make([]int, level+2)
+	for i := 0; i <= level; i++ {
+		indices[i] = i
+	}
+	indices[level+1] = depth + 1
+	return indices
+}
+
+func rnsChainOfModuli(rings []*negacyclic.RNSRing) []*big.Int {
+	res := make([]*big.Int, len(rings))
+	for l, ring := range rings {
+		res[l] = new(big.Int).Set(ring.Q)
+	}
+	return res
+}
+
+// toLimbs replaces the polynomials of the ciphertext by their residues modulo
+// the primes of its level.
+func (ins *Instance) toLimbs(ciph *Ciphertext) {
+	ring := ins.rings[ciph.level]
+	ciph.rns = make([]*negacyclic.RNSPolynomial, len(ciph.c))
+	for k := range ciph.c {
+		ciph.rns[k] = ring.FromPolynomial(ciph.c[k])
+	}
+	ciph.c = nil
+	ciph.moduli = ring.Moduli
+}
+
+func (ins *Instance) decryptRNS(sk *SecretKey, c *Ciphertext) *Plaintext {
+	ring := ins.rings[c.level]
+	s := ring.ToNTT(ring.FromPolynomial(sk.s.Polynomial()))
+	d := c.Degree()
+	decrypted := ring.ToNTT(c.rns[d])
+	for k := d - 1; k >= 0; k-- {
+		decrypted = ring.Add(ring.Hadamard(decrypted, s), ring.ToNTT(c.rns[k]))
+	}
+	return &Plaintext{m: ring.ToPolynomial(ring.FromNTT(decrypted)), scale: new(big.Float).Copy(c.scale)}
+}
+
+func (ins *Instance) addRNS(c1, c2 *Ciphertext) *Ciphertext {
+	ring := ins.rings[c1.level]
+	if c1.Degree() < c2.Degree() {
+		c1, c2 = c2, c1
+	}
+	sum := make([]*negacyclic.RNSPolynomial, len(c1.rns))
+	for k := range sum {
+		sum[k] = ring.FromNTT(c1.rns[k]).Copy()
+		if k < len(c2.rns) {
+			sum[k] = ring.Add(sum[k], ring.FromNTT(c2.rns[k]))
+		}
+	}
+	return c1.withResidues(sum)
+}
+
+func (ins *Instance) mulIntRNS(ciph *Ciphertext, k *big.Int) *Ciphertext {
+	ring := ins.rings[ciph.level]
+	prod := make([]*negacyclic.RNSPolynomial, len(ciph.rns))
+	for i := range prod {
+		prod[i] = ring.MulScalar(ciph.rns[i], k)
+	}
+	return ciph.withResidues(prod)
+}
+
+func (ins *Instance) addPlainRNS(ciph *Ciphertext, plt *Plaintext) *Ciphertext {
+	ring := ins.rings[ciph.level]
+	sum := make([]*negacyclic.RNSPolynomial, len(ciph.rns))
+	for k := range sum {
+		sum[k] = ring.FromNTT(ciph.rns[k]).Copy()
+	}
+	sum[0] = ring.Add(sum[0], ring.FromPolynomial(plt.m))
+	return ciph.withResidues(sum)
+}
+
+func (ins *Instance) mulPlainRNS(ciph *Ciphertext, plt *Plaintext) *Ciphertext {
+	ring := ins.rings[ciph.level]
+	pt := ring.ToNTT(ring.FromPolynomial(plt.m))
+	prod := make([]*negacyclic.RNSPolynomial, len(ciph.rns))
+	for k := range prod {
+		prod[k] = ring.FromNTT(ring.Hadamard(ring.ToNTT(ciph.rns[k]), pt))
+	}
+	res := ciph.withResidues(prod)
+	res.scale.Mul(ciph.scale, plt.scale)
+	return res
+}
+
+// mulNoRelinRNS computes the tensor product limb by limb, in evaluation form.
+func (ins *Instance) mulNoRelinRNS(c1, c2 *Ciphertext) *Ciphertext {
+	ring := ins.rings[c1.level]
+	x := make([]*negacyclic.RNSPolynomial, len(c1.rns))
+	for i := range x {
+		x[i] = ring.ToNTT(c1.rns[i])
+	}
+	y := x
+	if c2 != c1 {
+		y = make([]*negacyclic.RNSPolynomial, len(c2.rns))
+		for j := range y {
+			y[j] = ring.ToNTT(c2.rns[j])
+		}
+	}
+	d := make([]*negacyclic.RNSPolynomial, len(x)+len(y)-1) // d_k = sum_{i+j=k} x_i y_j
+	parallel(len(d), func(k int) {
+		for i := range x {
+			if j := k - i; j >= 0 && j < len(y) {
+				if d[k] == nil {
+					d[k] = ring.Hadamard(x[i], y[j])
+				} else {
+					d[k] = ring.Add(d[k], ring.Hadamard(x[i], y[j]))
+				}
+			}
+		}
+		d[k] = ring.FromNTT(d[k])
+	})
+	res := c1.withResidues(d)
+	res.scale.Mul(c1.scale, c2.scale)
+	return res
+}
+
+// relinearizeRNS switches the component of s^2 to s, with the evaluation key
+// decomposed by prime.
+func (ins *Instance) relinearizeRNS(evk *EvaluationKey, ciph *Ciphertext) *Ciphertext {
+	ring := ins.rings[ciph.level]
+	nearestA, nearestB := ins.switchKeyRNS(evk, ring.FromNTT(ciph.rns[2]), ciph.level) // ⌊P^{-1} Σ [d2]_{q_i} evk_i⌉
+	return ciph.withResidues([]*negacyclic.RNSPolynomial{
+		ring.Add(ring.FromNTT(ciph.rns[0]), nearestB),
+		ring.Add(ring.FromNTT(ciph.rns[1]), nearestA),
+	})
+}
+
+// switchingKeyRNS is the RNS counterpart of switchingKey: it returns the keys
+// (b'_i, a'_i) switching from `target` to s, for each prime q_i of the chain,
+// in evaluation form (see the top of this file).
+func (ins *Instance) switchingKeyRNS(s *negacyclic.Vector, target *negacyclic.Polynomial) *EvaluationKey {
+	ring := ins.keyRings[ins.Depth]
+	sNTT := ring.ToNTT(ring.FromPolynomial(s.Polynomial()))
+	// P Q_i target is P target modulo q_i, and 0 modulo the other primes.
+	pTarget := ring.ToNTT(ring.MulScalar(ring.FromPolynomial(target), ins.pEv))
+	seed := negacyclic.NewSeed()
+	a := negacyclic.UniformRNSFromSeed(ins.N, ring.Moduli, ins.Depth+1, seed)
+	b := make([]*negacyclic.RNSPolynomial, len(a))
+	for i := range b {
+		e := negacyclic.VectorFromSlice(negacyclic.DG(ins.N, ins.Sigma)).Polynomial()
+		b[i] = ring.Sub(ring.ToNTT(ring.FromPolynomial(e)), ring.Hadamard(a[i], sNTT))
+		b[i] = ring.Add(b[i], limbOnly(pTarget, i)) // b'_i: -a'_i s + e'_i + P Q_i target
+	}
+	return &EvaluationKey{
+		digitB:  b,
+		digitA:  a,
+		seed:    seed,
+		modulus: new(big.Int).Mul(ins.FirstModulus(), ins.pEv),
+		moduli:  ring.Moduli,
+	}
+}
+
+// switchKeyRNS is the RNS counterpart of switchKey: it returns
+// ⌊P^{-1} Σ_i [d]_{q_i} swk_i⌉ modulo q_0 * ... * q_level, for d of the given
+// level in coefficient form, and the keys swk_i of swk.
+func (ins *Instance) switchKeyRNS(swk *EvaluationKey, d *negacyclic.RNSPolynomial, level int) (*negacyclic.RNSPolynomial, *negacyclic.RNSPolynomial) {
+	ring := ins.keyRings[level]
+	indices := keyIndices(level, ins.Depth)
+	digits := make([]*negacyclic.RNSPolynomial, level+1)
+	parallel(len(digits), func(i int) {
+		digits[i] = ring.ToNTT(ring.LiftLimb(d.Coeffs[i], ring.Moduli[i]))
+	})
+	var nearest [2]*negacyclic.RNSPolynomial
+	parallel(2, func(k int) {
+		keys := swk.digitA
+		if k == 1 {
+			keys = swk.digitB
+		}
+		sum := ring.Hadamard(digits[0], keys[0].Select(indices))
+		for i := 1; i < len(digits); i++ {
+			sum = ring.Add(sum, ring.Hadamard(digits[i], keys[i].Select(indices)))
+		}
+		nearest[k] = ring.DivRoundByLastModulus(ring.FromNTT(sum))
+	})
+	return nearest[0], nearest[1]
+}
+
+// checkDigitKeys is the RNS counterpart of checkSwitchingKey: it checks that
+// `b'_i + a'_i s - P Q_i target` is small, for each key (b'_i, a'_i) of evk.
+func (ins *Instance) checkDigitKeys(name string, evk *EvaluationKey, s *negacyclic.Vector, target *negacyclic.Polynomial) error {
+	if !ins.matchesDigitKeys(evk) {
+		return fmt.Errorf("%w: %s key not decomposed by the primes of the instance", ErrInconsistentKey, name)
+	}
+	ring := ins.keyRings[ins.Depth]
+	sNTT := ring.ToNTT(ring.FromPolynomial(s.Polynomial()))
+	pTarget := ring.ToNTT(ring.MulScalar(ring.FromPolynomial(target), ins.pEv))
+	for i := range evk.digitB {
+		small := ring.Add(evk.digitB[i], ring.Hadamard(evk.digitA[i], sNTT))
+		small = ring.Sub(small, limbOnly(pTarget, i))
+		if err := ins.checkResidual(name, ring.ToPolynomial(ring.FromNTT(small))); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// matchesDigitKeys reports whether evk is decomposed by the primes of the
+// instance, with keys modulo its primes and P.
+func (ins *Instance) matchesDigitKeys(evk *EvaluationKey) bool {
+	moduli := ins.keyRings[ins.Depth].Moduli
+	if len(evk.digitB) != ins.Depth+1 || len(evk.digitA) != len(evk.digitB) ||
+		len(evk.moduli) != len(moduli) || evk.dimension() != ins.N {
+		return false
+	}
+	for i, q := range moduli {
+		if evk.moduli[i] != q {
+			return false
+		}
+	}
+	return true
+}
+
+// digitsBound returns q_0 + ... + q_l, for the modulus q_0 * ... * q_l of a
+// level of the chain: the digits of key switching are bounded by q_i / 2.
+func (ins *Instance) digitsBound(modulus *big.Int) *big.Int {
+	sum := new(big.Int)
+	product := big.NewInt(1)
+	for _, q := range ins.rings[ins.Depth].Moduli {
+		bigQ := new(big.Int).SetUint64(q)
+		if product.Mul(product, bigQ).Cmp(modulus) > 0 {
+			break
+		}
+		sum.Add(sum, bigQ)
+	}
+	return sum
+}
+
+// limbOnly returns the polynomial whose i-th limb is the one of x, and whose
+// other limbs are zero, in the form of x.
+func limbOnly(x *negacyclic.RNSPolynomial, i int) *negacyclic.RNSPolynomial {
+	limbs := make([][]uint64, len(x.Coeffs))
+	for j := range limbs {
+		if j == i {
+			limbs[j] = x.Coeffs[i]
+		} else {
+			limbs[j] = make([]uint64, len(x.Coeffs[j]))
+		}
+	}
+	return negacyclic.NewRNSPolynomial(limbs, x.IsNTT())
+}
+
+// dropLevelRNS drops the residues of the ciphertext modulo the primes above
+// q_level. The ciphertext is left at its level.
+func (ins *Instance) dropLevelRNS(ciph *Ciphertext, level int) {
+	ring := ins.rings[level]
+	for k := range ciph.rns {
+		ciph.rns[k] = ring.DropLastModuli(ciph.rns[k])
+	}
+	ciph.moduli = ring.Moduli
+}
+
+// rescaleRNS divides the ciphertext by q_l, ..., q_{level+1}, one prime at a
+// time.
+func (ins *Instance) rescaleRNS(ciph *Ciphertext, level int) {
+	ring := ins.rings[ciph.level]
+	for k := range ciph.rns {
+		x := ring.FromNTT(ciph.rns[k])
+		for l := ciph.level; l > level; l-- {
+			x = ins.rings[l].DivRoundByLastModulus(x)
+		}
+		ciph.rns[k] = x
+	}
+	for l := ciph.level; l > level; l-- {
+		ins.rescaleBounds(ciph, new(big.Int).SetUint64(ins.rings[l].Moduli[l]))
+	}
+	ciph.level = level
+	ciph.ql = new(big.Int).Set(ins.rings[level].Q)
+	ciph.moduli = ins.rings[level].Moduli
+	ciph.scale = rescaledScale(ciph.scale, ring.Q, ciph.ql)
+}
+
+// residuesString returns the residues of the first coefficient of x.
+func residuesString(x *negacyclic.RNSPolynomial) string {
+	residues := make([]string, len(x.Coeffs))
+	for i, limb := range x.Coeffs {
+		residues[i] = strconv.FormatUint(limb[0], 10)
+	}
+	return "(" + strings.Join(residues, ", ") + ")"
+}
@@ -0,0 +1,90 @@
+package ckks
+
+import (
+	"ckks/negacyclic"
+)
+
+// RotationKeys contains, for each generated step k, the key switching from
+// s(X^(5^k)) to the secret key s (see GenerateRotationKeys). They are needed to
+// rotate the slots of ciphertexts.
+type RotationKeys struct {
+	keys map[int]*EvaluationKey
+}
+
+// GenerateRotationKeys returns the rotation keys of the given secret key, for
+// the given steps. Steps are read modulo N/2, and may be negative.
+func (ins *Instance) GenerateRotationKeys(sk *SecretKey, steps []int) *RotationKeys {
+	rtk := &RotationKeys{keys: make(map[int]*EvaluationKey)}
+	for _, k := range steps {
+		k = ins.rotationStep(k)
+		if _, ok := rtk.keys[k]; ok || k == 0 {
+			continue
+		}
+		g := negacyclic.GaloisElement(k, ins.N)
+		rtk.keys[k] = ins.switchingKey(sk.s, negacyclic.Automorphism(sk.s.Polynomial(), g))
+	}
+	return rtk
+}
+
+// Rotate returns a ciphertext whose i-th slot is the (i+k)-th slot of ciph,
+// i.e. it rotates the decoded vector by k positions to the left, for k read
+// modulo N/2. It applies the automorphism X -> X^(5^k), and switches the key
+// back to s. It returns ErrMissingRotationKey if no key was generated for k,
+// and ErrCiphertextDegree if ciph is not relinearized.
+func (ins *Instance) Rotate(rtk *RotationKeys, ciph *Ciphertext, k int) (*Ciphertext, error) {
+	if ciph.Degree() != 1 {
+		return nil, ErrCiphertextDegree
+	}
+	k = ins.rotationStep(k)
+	if k == 0 {
+		return ciph.Clone(), nil
+	}
+	swk, ok := rtk.keys[k]
+	if !ok {
+		return nil, ErrMissingRotationKey
+	}
+	return ins.applyAutomorphism(swk, ciph, negacyclic.GaloisElement(k, ins.N)), nil
+}
+
+// Conjugate returns a ciphertext whose slots are the complex conjugates of the
+// slots of ciph. It applies the automorphism X -> X^(-1), and switches the key
+// back to s with the conjugation key (see Key.Conjugation). It returns
+// ErrCiphertextDegree if ciph is not relinearized.
+func (ins *Instance) Conjugate(cjk *EvaluationKey, ciph *Ciphertext) (*Ciphertext, error) {
+	if ciph.Degree() != 1 {
+		return nil, ErrCiphertextDegree
+	}
+	return ins.applyAutomorphism(cjk, ciph, negacyclic.ConjugationElement(ins.N)), nil
+}
+
+// applyAutomorphism returns the ciphertext (b(X^g), a(X^g)), which decrypts
+// under s(X^g), with its key switched to s with swk. In RNS mode, the
+// automorphism and key switching are computed limb by limb (see rns.go).
+func (ins *Instance) applyAutomorphism(swk *EvaluationKey, ciph *Ciphertext, g int) *Ciphertext {
+	if ins.RNS {
+		ring := ins.rings[ciph.level]
+		b := ring.Automorphism(ring.FromNTT(ciph.rns[0]), g)
+		a := ring.Automorphism(ring.FromNTT(ciph.rns[1]), g)
+		nearestA, nearestB := ins.switchKeyRNS(swk, a, ciph.level)
+		return ins.keySwitchBounds(ciph.withResidues([]*negacyclic.RNSPolynomial{
+			ring.Add(b, nearestB),
+			nearestA,
+		}))
+	}
+	b := negacyclic.Automorphism(ins.coefficients(ciph.c[0], ciph), g)
+	a := negacyclic.Automorphism(ins.coefficients(ciph.c[1], ciph), g)
+	nearestA, nearestB := ins.switchKey(swk, a)
+	return ins.keySwitchBounds(ciph.withComponents([]*negacyclic.Polynomial{
+		negacyclic.Add(b, nearestB).Mod(ciph.ql),
+		nearestA.Mod(ciph.ql),
+	}))
+}
+
+// rotationStep returns k modulo N/2, in [0, N/2).
+func (ins *Instance) rotationStep(k int) int {
+	k %= ins.N / 2
+	if k < 0 {
+		k += ins.N / 2
+	}
+	return k
+}
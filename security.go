@@ -0,0 +1,208 @@
+package ckks
+
+import (
+	"math"
+)
+
+// This file estimates the security of an instance, as the cost of the best
+// known attacks against the RLWE problems of its keys. The largest modulus is
+// the one of the switching keys, P * q_L, so that it determines the security
+// of the whole instance.
+//
+// The attacks are lattice reductions with BKZ, whose cost for a block size β in
+// dimension d is modeled as 0.292 β + 16.4 + log2(8d) operations (sieving, as
+// in the tables of the homomorphic encryption standard), and whose output
+// satisfies the geometric series assumption, with root Hermite factor δ(β).
+//
+//   - Primal uSVP: the error is the unique shortest vector of the Kannan
+//     embedding, found when σ √β <= δ^(2β - d) Vol^(1/d) [ADPS16].
+//   - Dual: a short vector of the dual lattice distinguishes samples from
+//     uniform, with advantage ε = exp(-2π^2 (ℓσ/q)^2) for a vector of length ℓ;
+//     a sieve outputs 2^(0.2075 β) such vectors per reduction [Alb17].
+//   - Hybrid: k coordinates of the sparse secret are guessed to be zero, which
+//     happens with probability C(N-h, k) / C(N, k), and the remaining
+//     instance of dimension N - k is attacked as above.
+//
+// In the first two, the secret coordinates are rescaled to the size of the
+// error, which accounts for the small secrets of the scheme. These are
+// estimates, not proofs: the costs only guide the choice of parameters.
+
+// DefaultMinimumSecurity is the bit security under which NewInstance returns
+// ErrWarningInsecure, unless the parameters set another minimum (see
+// Parameters.MinSecurity and Instance.SecurityLevel).
+const DefaultMinimumSecurity = 128
+
+// maxBlockSize bounds the block sizes of the estimates. It costs more than
+// 2^450 operations, so that larger block sizes are irrelevant.
+const maxBlockSize = 1500
+
+// SecurityEstimate contains the log2 of the cost of each attack.
+type SecurityEstimate struct {
+	PrimalUSVP float64
+	Dual       float64
+	Hybrid     float64
+}
+
+// Level returns the bit security, i.e. the cost of the cheapest attack.
+func (est SecurityEstimate) Level() float64 {
+	return math.Min(est.PrimalUSVP, math.Min(est.Dual, est.Hybrid))
+}
+
+// EstimateSecurity estimates the cost of the attacks against RLWE in dimension
+// n, modulo a modulus of logQ bits, for errors of standard deviation sigma and
+// secrets with the given Hamming weight.
+func EstimateSecurity(n int, logQ, sigma float64, hamming int) SecurityEstimate {
+	sigmaS := math.Sqrt(float64(hamming) / float64(n))
+	est := SecurityEstimate{
+		PrimalUSVP: primalCost(n, n, logQ, sigma, sigmaS),
+		Dual:       dualCost(n, n, logQ, sigma, sigmaS),
+		Hybrid:     math.Inf(1),
+	}
+	for i := 1; i <= 16; i++ {
+		k := (n - hamming) * i / 17
+		if k == 0 {
+			continue
+		}
+		sigmaK := math.Sqrt(float64(hamming) / float64(n-k))
+		cost := math.Min(primalCost(n-k, n, logQ, sigma, sigmaK), dualCost(n-k, n, logQ, sigma, sigmaK))
+		est.Hybrid = math.Min(est.Hybrid, cost-log2ZeroGuess(n, hamming, k))
+	}
+	return est
+}
+
+// SecurityEstimate returns the estimated costs of the attacks against the
+// instance (see EstimateSecurity).
+func (ins *Instance) SecurityEstimate() SecurityEstimate {
+	return ins.security
+}
+
+// MinimumSecurity returns the bit security under which the instance is
+// insecure: Parameters.MinSecurity if set, and DefaultMinimumSecurity
+// otherwise.
+func (ins *Instance) MinimumSecurity() float64 {
+	if ins.MinSecurity != 0 {
+		return ins.MinSecurity
+	}
+	return DefaultMinimumSecurity
+}
+
+// SecurityLevel returns the estimated bit security of the instance.
+func (ins *Instance) SecurityLevel() float64 {
+	return ins.security.Level()
+}
+
+// estimateSecurity estimates the security of the instance, for the modulus
+// P * q_L of the switching keys.
+func (ins *Instance) estimateSecurity() SecurityEstimate {
+	logQ := float64(ins.FirstModulus().BitLen() + ins.pEv.BitLen())
+	return EstimateSecurity(ins.N, logQ, errorStdDev(ins.Sigma), ins.Hamming)
+}
+
+// errorStdDev returns the standard deviation of the errors sampled by
+// negacyclic.DG, which scales normal samples by the square root of Sigma.
+func errorStdDev(sigma float64) float64 {
+	return math.Sqrt(sigma)
+}
+
+// primalCost returns the cost of the primal uSVP attack in dimension n with m
+// samples at most.
+func primalCost(n, maxSamples int, logQ, sigma, sigmaS float64) float64 {
+	logNu := math.Log2(sigma / sigmaS)
+	best := math.Inf(1)
+	for _, m := range sampleCounts(maxSamples) {
+		d := n + m + 1
+		logVol := (float64(m)*logQ + float64(n)*logNu) / float64(d)
+		succeeds := func(beta int) bool {
+			lhs := math.Log2(sigma * math.Sqrt(float64(beta)))
+			return lhs <= float64(2*beta-d)*log2RootHermite(beta)+logVol
+		}
+		top := d
+		if top > maxBlockSize {
+			top = maxBlockSize
+		}
+		if !succeeds(top) {
+			continue
+		}
+		// Smallest successful block size, by bisection.
+		low, high := 50, top
+		for low < high {
+			mid := (low + high) / 2
+			if succeeds(mid) {
+				high = mid
+			} else {
+				low = mid + 1
+			}
+		}
+		best = math.Min(best, bkzCost(low, d))
+	}
+	if math.IsInf(best, 1) {
+		return bkzCost(maxBlockSize, n)
+	}
+	return best
+}
+
+// dualCost returns the cost of the dual attack in dimension n with m samples at
+// most.
+func dualCost(n, maxSamples int, logQ, sigma, sigmaS float64) float64 {
+	logC := math.Log2(sigmaS / sigma)
+	best := math.Inf(1)
+	for _, m := range sampleCounts(maxSamples) {
+		d := n + m
+		logVol := float64(n) * (logQ + logC) / float64(d)
+		for beta := 50; beta <= maxBlockSize && beta <= d; beta += 2 {
+			// log2 of ℓσ/q, for ℓ = δ^d Vol^(1/d)
+			logTau := float64(d)*log2RootHermite(beta) + logVol + math.Log2(sigma) - logQ
+			if bkzCost(beta, d) >= best {
+				break
+			}
+			if logTau > 0 {
+				continue // no distinguishing advantage yet
+			}
+			tau := math.Exp2(logTau)
+			logInvAdvantage := 2 * math.Pi * math.Pi * tau * tau * math.Log2E
+			repetitions := math.Max(0, 2*logInvAdvantage-0.2075*float64(beta))
+			best = math.Min(best, bkzCost(beta, d)+repetitions)
+		}
+	}
+	if math.IsInf(best, 1) {
+		return bkzCost(maxBlockSize, n)
+	}
+	return best
+}
+
+// sampleCounts returns the numbers of samples tried by the attacks.
+func sampleCounts(maxSamples int) []int {
+	counts := make([]int, 0, 32)
+	for i := 1; i <= 32; i++ {
+		if m := maxSamples * i / 32; m > 0 {
+			counts = append(counts, m)
+		}
+	}
+	return counts
+}
+
+// bkzCost returns the log2 of the cost of BKZ with block size beta in
+// dimension d.
+func bkzCost(beta, d int) float64 {
+	return 0.292*float64(beta) + 16.4 + math.Log2(8*float64(d))
+}
+
+// log2RootHermite returns the log2 of the root Hermite factor of BKZ with
+// block size beta.
+func log2RootHermite(beta int) float64 {
+	b := float64(beta)
+	return math.Log2(b/(2*math.Pi*math.E)*math.Pow(math.Pi*b, 1/b)) / (2 * (b - 1))
+}
+
+// log2ZeroGuess returns the log2 of the probability that k given coordinates
+// of a secret of dimension n and Hamming weight h are zero, C(n-h, k) / C(n, k).
+func log2ZeroGuess(n, h, k int) float64 {
+	return (logBinomial(n-h, k) - logBinomial(n, k)) / math.Ln2
+}
+
+func logBinomial(n, k int) float64 {
+	a, _ := math.Lgamma(float64(n + 1))
+	b, _ := math.Lgamma(float64(k + 1))
+	c, _ := math.Lgamma(float64(n - k + 1))
+	return a - b - c
+}
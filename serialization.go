@@ -0,0 +1,1051 @@
+package ckks
+
+import (
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	"math"
+	"math/big"
+	"sort"
+	"strconv"
+
+	"ckks/negacyclic"
+)
+
+// This file implements the binary serialization of keys, plaintexts,
+// ciphertexts and parameters (see MarshalBinary and UnmarshalBinary).
+//
+// Every object starts with a header: the magic bytes "CKKS", the format
+// version and the kind of the object. Integers are written in big-endian order.
+// Polynomials are packed with a fixed number of bits per coefficient: the bit
+// length of their modulus, or of each prime in RNS mode. Uniform polynomials
+// expanded from a seed (see PublicKey.Seed) are replaced by the seed.
+//
+// Decoding is strict: it fails with ErrMalformedData on truncated or trailing
+// data, on coefficients exceeding their modulus, and on inconsistent headers.
+// Keys are decoded in evaluation form with respect to a ZMultiplier rebuilt
+// from the bound of their instance, which is part of the encoding. Evaluation
+// keys of RNS instances are written limb by limb in evaluation form.
+
+// SerializationVersion is the version of the binary format written by the
+// MarshalBinary methods. Other versions are rejected on decoding, with
+// ErrUnsupportedVersion.
+const SerializationVersion = 1
+
+var serializationMagic = []byte("CKKS")
+
+// Kinds of serialized objects.
+const (
+	kindPlaintext byte = iota + 1
+	kindCiphertext
+	kindSecretKey
+	kindPublicKey
+	kindEvaluationKey
+	kindKey
+	kindRotationKeys
+	kindParameters
+	kindParameterSet
+)
+
+// maxDimension and maxDegree bound the dimension and the degree of
+// ciphertexts read from serialized data, so that malformed inputs cannot
+// trigger large allocations.
+const (
+	maxDimension = 1 << 20
+	maxDegree    = 16
+)
+
+// MarshalBinary encodes the plaintext: its dimension, its scale, and its
+// coefficients in two's complement, on the bit length of the largest one.
+func (plt *Plaintext) MarshalBinary() ([]byte, error) {
+	if plt.m.IsNTT() {
+		return nil, ErrEvaluationForm
+	}
+	width := 1
+	for _, coeff := range plt.m.Coeffs {
+		if coeff.BitLen()+1 > width {
+			width = coeff.BitLen() + 1
+		}
+	}
+	modulus := new(big.Int).Lsh(big.NewInt(1), uint(width))
+	values := make([]*big.Int, len(plt.m.Coeffs))
+	for i, coeff := range plt.m.Coeffs {
+		values[i] = new(big.Int).Mod(coeff, modulus)
+	}
+	e := newEncoder(kindPlaintext)
+	e.uint32(plt.m.Deg())
+	e.scale(plt.scale)
+	e.uint32(width)
+	e.packed(values, width)
+	return e.buf, nil
+}
+
+// UnmarshalBinary decodes a plaintext encoded with MarshalBinary.
+func (plt *Plaintext) UnmarshalBinary(data []byte) error {
+	d := newDecoder(data, kindPlaintext)
+	n := d.dimension()
+	scale := d.scale()
+	width := d.uint32()
+	if d.err == nil && width == 0 {
+		d.fail("zero coefficient width")
+	}
+	values := d.packed(n, width, nil)
+	if err := d.finish(); err != nil {
+		return err
+	}
+	modulus := new(big.Int).Lsh(big.NewInt(1), uint(width))
+	half := new(big.Int).Rsh(modulus, 1)
+	for _, value := range values {
+		if value.Cmp(half) >= 0 {
+			value.Sub(value, modulus)
+		}
+	}
+	*plt = Plaintext{m: negacyclic.PolynomialFromSlice(values), scale: scale}
+	return nil
+}
+
+// MarshalBinary encodes the ciphertext: its dimension, level, degree, scale,
+// bounds of the message and the noise, and modulus, the primes of its level in
+// RNS mode, and its components in coefficient form. The component a of a fresh
+// secret-key ciphertext is replaced by its seed. It returns ErrEvaluationForm
+// if the ciphertext is in evaluation form (see Instance.FromNTT).
+func (ciph *Ciphertext) MarshalBinary() ([]byte, error) {
+	for k := 0; k <= ciph.Degree(); k++ {
+		if (ciph.rns != nil && ciph.rns[k].IsNTT()) || (ciph.rns == nil && ciph.c[k].IsNTT()) {
+			return nil, ErrEvaluationForm
+		}
+	}
+	e := newEncoder(kindCiphertext)
+	if ciph.rns != nil {
+		e.uint32(len(ciph.rns[0].Coeffs[0]))
+	} else {
+		e.uint32(ciph.c[0].Deg())
+	}
+	e.uint32(ciph.level)
+	e.uint32(ciph.Degree())
+	e.scale(ciph.scale)
+	e.scale(ciph.nu)
+	e.scale(ciph.noise)
+	e.bigInt(ciph.ql)
+	e.flag(ciph.rns != nil)
+	for _, q := range ciph.moduli {
+		e.uint64(q)
+	}
+	e.flag(ciph.seed != nil)
+	for k := 0; k <= ciph.Degree(); k++ {
+		switch {
+		case k == 1 && ciph.seed != nil:
+			e.buf = append(e.buf, ciph.seed...)
+		case ciph.rns != nil:
+			for i, q := range ciph.moduli {
+				e.packedWords(ciph.rns[k].Coeffs[i], bitLen64(q))
+			}
+		default:
+			e.packed(reduce(ciph.c[k], ciph.ql), ciph.ql.BitLen())
+		}
+	}
+	return e.buf, nil
+}
+
+// UnmarshalBinary decodes a ciphertext encoded with MarshalBinary. In RNS mode,
+// the product of the primes must be the modulus of the ciphertext. Its degree
+// must be at most 16. The ciphertext is not checked against any instance: see
+// Instance.CheckCiphertext.
+func (ciph *Ciphertext) UnmarshalBinary(data []byte) error {
+	d := newDecoder(data, kindCiphertext)
+	n := d.dimension()
+	level := d.uint32()
+	degree := d.uint32()
+	if d.err == nil && (degree == 0 || degree > maxDegree) {
+		d.fail("bad ciphertext degree")
+	}
+	scale := d.scale()
+	nu, noise := d.bound(), d.bound()
+	ql := d.modulus()
+	isRNS := d.flag()
+	var moduli []uint64
+	if isRNS && d.err == nil {
+		if level+1 > len(d.data)/8 {
+			d.fail("truncated data")
+		}
+		product := big.NewInt(1)
+		for l := 0; l <= level && d.err == nil; l++ {
+			q := d.uint64()
+			if q < 2 {
+				d.fail("bad RNS prime")
+			}
+			moduli = append(moduli, q)
+			product.Mul(product, new(big.Int).SetUint64(q))
+		}
+		if d.err == nil && product.Cmp(ql) != 0 {
+			d.fail("RNS primes do not match the modulus")
+		}
+	}
+	seeded := d.flag()
+	if d.err != nil {
+		return d.err
+	}
+	res := &Ciphertext{level: level, ql: ql, scale: scale, nu: nu, noise: noise, moduli: moduli}
+	if isRNS {
+		res.rns = make([]*negacyclic.RNSPolynomial, degree+1)
+	} else {
+		res.c = make([]*negacyclic.Polynomial, degree+1)
+	}
+	for k := 0; k <= degree && d.err == nil; k++ {
+		if k == 1 && seeded {
+			res.seed = append([]byte(nil), d.next(negacyclic.SeedSize)...)
+			if d.err != nil {
+				break
+			}
+			a := negacyclic.UniformModFromSeed(n, ql, res.seed)
+			if isRNS {
+				res.rns[k] = residues(a, moduli)
+			} else {
+				res.c[k] = negacyclic.PolynomialFromSlice(a).Mod(ql)
+			}
+			continue
+		}
+		if isRNS {
+			limbs := make([][]uint64, len(moduli))
+			for i, q := range moduli {
+				limbs[i] = d.packedWords(n, bitLen64(q), q)
+			}
+			res.rns[k] = &negacyclic.RNSPolynomial{Coeffs: limbs}
+		} else {
+			res.c[k] = negacyclic.PolynomialFromSlice(d.packed(n, ql.BitLen(), ql)).Mod(ql)
+		}
+	}
+	if err := d.finish(); err != nil {
+		return err
+	}
+	*ciph = *res
+	return nil
+}
+
+// MarshalBinary encodes the secret key: its dimension, and its coefficients on
+// two bits each.
+func (sk *SecretKey) MarshalBinary() ([]byte, error) {
+	values := make([]uint64, len(sk.s.Coeffs))
+	for i, coeff := range sk.s.Coeffs {
+		values[i] = uint64(coeff) & 3 // -1 is 3
+	}
+	e := newEncoder(kindSecretKey)
+	e.uint32(len(values))
+	e.packedWords(values, 2)
+	return e.buf, nil
+}
+
+// UnmarshalBinary decodes a secret key encoded with MarshalBinary. Its
+// coefficients must lie in {0, 1, -1}.
+func (sk *SecretKey) UnmarshalBinary(data []byte) error {
+	d := newDecoder(data, kindSecretKey)
+	n := d.dimension()
+	values := d.packedWords(n, 2, 4)
+	coeffs := make([]int, len(values))
+	for i, value := range values {
+		if value == 2 {
+			d.fail("secret key coefficient out of {0, ±1}")
+		}
+		coeffs[i] = int(int64(value<<62) >> 62)
+	}
+	if err := d.finish(); err != nil {
+		return err
+	}
+	*sk = SecretKey{s: negacyclic.VectorFromSlice(coeffs)}
+	return nil
+}
+
+// MarshalBinary encodes the public key: its dimension, the modulus q_L, the
+// bound of the ZMultiplier of its evaluation form, the polynomial b, and the
+// seed of a.
+func (pk *PublicKey) MarshalBinary() ([]byte, error) {
+	e := newEncoder(kindPublicKey)
+	e.keyPair(pk.b, pk.a, pk.seed, pk.modulus, pk.zm)
+	return e.buf, nil
+}
+
+// UnmarshalBinary decodes a public key encoded with MarshalBinary.
+func (pk *PublicKey) UnmarshalBinary(data []byte) error {
+	return pk.unmarshal(data, newZMultiplierCache())
+}
+
+func (pk *PublicKey) unmarshal(data []byte, cache zMultiplierCache) error {
+	d := newDecoder(data, kindPublicKey)
+	kp := d.keyPair()
+	if err := d.finish(); err != nil {
+		return err
+	}
+	zm, err := cache.get(kp.n, kp.bound)
+	if err != nil {
+		return err
+	}
+	*pk = PublicKey{b: zm.ToNTT(kp.b), a: zm.ToNTT(kp.a), seed: kp.seed, modulus: kp.modulus, zm: zm}
+	return nil
+}
+
+// MarshalBinary encodes the evaluation key as PublicKey.MarshalBinary, for the
+// modulus P * q_L, after a flag set in RNS mode. In RNS mode, it encodes its
+// dimension, the primes q_0, ..., q_L, P, the polynomials b'_i in evaluation
+// form, and the seed of the a'_i.
+func (evk *EvaluationKey) MarshalBinary() ([]byte, error) {
+	e := newEncoder(kindEvaluationKey)
+	e.flag(evk.zm == nil)
+	if evk.zm == nil {
+		e.digitKeys(evk)
+	} else {
+		e.keyPair(evk.b, evk.a, evk.seed, evk.modulus, evk.zm)
+	}
+	return e.buf, nil
+}
+
+// UnmarshalBinary decodes an evaluation key encoded with MarshalBinary.
+func (evk *EvaluationKey) UnmarshalBinary(data []byte) error {
+	return evk.unmarshal(data, newZMultiplierCache())
+}
+
+func (evk *EvaluationKey) unmarshal(data []byte, cache zMultiplierCache) error {
+	d := newDecoder(data, kindEvaluationKey)
+	if d.flag() {
+		res := d.digitKeys()
+		if err := d.finish(); err != nil {
+			return err
+		}
+		*evk = *res
+		return nil
+	}
+	kp := d.keyPair()
+	if err := d.finish(); err != nil {
+		return err
+	}
+	zm, err := cache.get(kp.n, kp.bound)
+	if err != nil {
+		return err
+	}
+	*evk = EvaluationKey{b: zm.ToNTT(kp.b), a: zm.ToNTT(kp.a), seed: kp.seed, modulus: kp.modulus, zm: zm}
+	return nil
+}
+
+// MarshalBinary encodes the keys of the receiver which are not nil, so that
+// e.g. a key without its secret part can be sent to an evaluating party.
+func (key *Key) MarshalBinary() ([]byte, error) {
+	var parts [4][]byte
+	var err error
+	if key.Public != nil {
+		if parts[0], err = key.Public.MarshalBinary(); err != nil {
+			return nil, err
+		}
+	}
+	if key.Secret != nil {
+		if parts[1], err = key.Secret.MarshalBinary(); err != nil {
+			return nil, err
+		}
+	}
+	if key.Evaluation != nil {
+		if parts[2], err = key.Evaluation.MarshalBinary(); err != nil {
+			return nil, err
+		}
+	}
+	if key.Conjugation != nil {
+		if parts[3], err = key.Conjugation.MarshalBinary(); err != nil {
+			return nil, err
+		}
+	}
+	e := newEncoder(kindKey)
+	for _, part := range parts {
+		e.bytes(part)
+	}
+	return e.buf, nil
+}
+
+// UnmarshalBinary decodes a key encoded with MarshalBinary. Missing keys are
+// left nil.
+func (key *Key) UnmarshalBinary(data []byte) error {
+	return key.unmarshal(data, newZMultiplierCache())
+}
+
+func (key *Key) unmarshal(data []byte, cache zMultiplierCache) error {
+	d := newDecoder(data, kindKey)
+	var parts [4][]byte
+	for i := range parts {
+		parts[i] = d.bytes()
+	}
+	if err := d.finish(); err != nil {
+		return err
+	}
+	res := Key{}
+	if len(parts[0]) > 0 {
+		res.Public = new(PublicKey)
+		if err := res.Public.unmarshal(parts[0], cache); err != nil {
+			return err
+		}
+	}
+	if len(parts[1]) > 0 {
+		res.Secret = new(SecretKey)
+		if err := res.Secret.UnmarshalBinary(parts[1]); err != nil {
+			return err
+		}
+	}
+	if len(parts[2]) > 0 {
+		res.Evaluation = new(EvaluationKey)
+		if err := res.Evaluation.unmarshal(parts[2], cache); err != nil {
+			return err
+		}
+	}
+	if len(parts[3]) > 0 {
+		res.Conjugation = new(EvaluationKey)
+		if err := res.Conjugation.unmarshal(parts[3], cache); err != nil {
+			return err
+		}
+	}
+	*key = res
+	return nil
+}
+
+// MarshalBinary encodes the number of rotation keys, then each step followed
+// by its key, by increasing steps.
+func (rtk *RotationKeys) MarshalBinary() ([]byte, error) {
+	steps := make([]int, 0, len(rtk.keys))
+	for k := range rtk.keys {
+		steps = append(steps, k)
+	}
+	sort.Ints(steps)
+	e := newEncoder(kindRotationKeys)
+	e.uint32(len(steps))
+	for _, k := range steps {
+		data, err := rtk.keys[k].MarshalBinary()
+		if err != nil {
+			return nil, err
+		}
+		e.uint32(k)
+		e.bytes(data)
+	}
+	return e.buf, nil
+}
+
+// UnmarshalBinary decodes rotation keys encoded with MarshalBinary. The steps
+// must be increasing, and lie in (0, N/2).
+func (rtk *RotationKeys) UnmarshalBinary(data []byte) error {
+	return rtk.unmarshal(data, newZMultiplierCache())
+}
+
+func (rtk *RotationKeys) unmarshal(data []byte, cache zMultiplierCache) error {
+	d := newDecoder(data, kindRotationKeys)
+	count := d.uint32()
+	keys := make(map[int]*EvaluationKey)
+	previous := 0
+	for i := 0; i < count && d.err == nil; i++ {
+		k := d.uint32()
+		part := d.bytes()
+		if d.err != nil {
+			break
+		}
+		if k <= previous {
+			d.fail("rotation steps are not increasing")
+			break
+		}
+		previous = k
+		evk := new(EvaluationKey)
+		if err := evk.unmarshal(part, cache); err != nil {
+			return err
+		}
+		if 2*k >= evk.dimension() {
+			d.fail("rotation step out of (0, N/2)")
+		}
+		keys[k] = evk
+	}
+	if err := d.finish(); err != nil {
+		return err
+	}
+	*rtk = RotationKeys{keys: keys}
+	return nil
+}
+
+// MarshalBinary encodes the parameters.
+func (pars *Parameters) MarshalBinary() ([]byte, error) {
+	e := newEncoder(kindParameters)
+	e.parameters(pars)
+	return e.buf, nil
+}
+
+// UnmarshalBinary decodes parameters encoded with MarshalBinary.
+func (pars *Parameters) UnmarshalBinary(data []byte) error {
+	d := newDecoder(data, kindParameters)
+	res := d.parameters()
+	if err := d.finish(); err != nil {
+		return err
+	}
+	*pars = *res
+	return nil
+}
+
+// MarshalBinary encodes the parameters, then the moduli p, q0 and P, and the
+// primes of the RNS chain, if any.
+func (ps *ParameterSet) MarshalBinary() ([]byte, error) {
+	e := newEncoder(kindParameterSet)
+	e.parameters(&ps.Parameters)
+	e.bigInt(ps.Moduli.P)
+	e.bigInt(ps.Moduli.Q0)
+	e.bigInt(ps.Moduli.PEv)
+	e.uint32(len(ps.Moduli.RNS))
+	for _, q := range ps.Moduli.RNS {
+		e.uint64(q)
+	}
+	return e.buf, nil
+}
+
+// UnmarshalBinary decodes a parameter set encoded with MarshalBinary. The
+// moduli are checked when creating the instance (see
+// NewInstanceFromParameterSet).
+func (ps *ParameterSet) UnmarshalBinary(data []byte) error {
+	d := newDecoder(data, kindParameterSet)
+	res := &ParameterSet{Parameters: *d.parameters()}
+	res.Moduli.P = d.modulus()
+	res.Moduli.Q0 = d.modulus()
+	res.Moduli.PEv = d.modulus()
+	count := d.uint32()
+	if d.err == nil && count > len(d.data)/8 {
+		d.fail("truncated data")
+	}
+	for i := 0; i < count && d.err == nil; i++ {
+		res.Moduli.RNS = append(res.Moduli.RNS, d.uint64())
+	}
+	if err := d.finish(); err != nil {
+		return err
+	}
+	*ps = *res
+	return nil
+}
+
+// keyPair writes the key (b, a), whose coefficients are reduced modulo
+// `modulus`, and which is in evaluation form with respect to zm. The
+// polynomial a is replaced by its seed, if any.
+func (e *encoder) keyPair(b, a *negacyclic.RNSPolynomial, seed []byte, modulus *big.Int, zm *negacyclic.ZMultiplier) {
+	e.uint32(zm.N)
+	e.bigInt(modulus)
+	e.bigInt(zm.Bound)
+	e.packed(reduce(zm.FromNTT(b), modulus), modulus.BitLen())
+	e.flag(seed != nil)
+	if seed != nil {
+		e.buf = append(e.buf, seed...)
+	} else {
+		e.packed(reduce(zm.FromNTT(a), modulus), modulus.BitLen())
+	}
+}
+
+// digitKeys writes the keys (b'_i, a'_i) of an evaluation key in RNS mode.
+// The polynomials a'_i are replaced by their seed, if any.
+func (e *encoder) digitKeys(evk *EvaluationKey) {
+	e.uint32(evk.dimension())
+	e.uint32(len(evk.moduli))
+	for _, q := range evk.moduli {
+		e.uint64(q)
+	}
+	e.residues(evk.digitB, evk.moduli)
+	e.flag(evk.seed != nil)
+	if evk.seed != nil {
+		e.buf = append(e.buf, evk.seed...)
+	} else {
+		e.residues(evk.digitA, evk.moduli)
+	}
+}
+
+// keyPair is a decoded key (b, a), in coefficient form.
+type keyPair struct {
+	n              int
+	modulus, bound *big.Int
+	b, a           *negacyclic.Polynomial
+	seed           []byte
+}
+
+// keyPair reads a key written by encoder.keyPair. It returns nil on failure.
+func (d *decoder) keyPair() *keyPair {
+	kp := &keyPair{n: d.dimension(), modulus: d.modulus(), bound: d.modulus()}
+	if d.err == nil && !keyBoundFits(kp.modulus, kp.bound) {
+		d.fail("bound of the evaluation form inconsistent with the modulus")
+	}
+	b := d.packed(kp.n, kp.modulus.BitLen(), kp.modulus)
+	var a []*big.Int
+	if d.flag() {
+		kp.seed = append([]byte(nil), d.next(negacyclic.SeedSize)...)
+		if d.err == nil {
+			a = negacyclic.UniformModFromSeed(kp.n, kp.modulus, kp.seed)
+		}
+	} else {
+		a = d.packed(kp.n, kp.modulus.BitLen(), kp.modulus)
+	}
+	if d.err != nil {
+		return nil
+	}
+	kp.b = negacyclic.PolynomialFromSlice(b).Mod(kp.modulus)
+	kp.a = negacyclic.PolynomialFromSlice(a).Mod(kp.modulus)
+	return kp
+}
+
+// digitKeys reads the keys written by encoder.digitKeys: at least two distinct
+// primes of at most MaxRNSModulusBitLen bits, and a key for each but the last
+// one. It returns nil on failure.
+func (d *decoder) digitKeys() *EvaluationKey {
+	n := d.dimension()
+	count := d.uint32()
+	if d.err == nil && (count < 2 || count > maxDepth+2) {
+		d.fail("bad number of RNS primes")
+	}
+	evk := &EvaluationKey{modulus: big.NewInt(1)}
+	seen := make(map[uint64]bool)
+	for i := 0; i < count && d.err == nil; i++ {
+		q := d.uint64()
+		if q < 2 || bitLen64(q) > negacyclic.MaxRNSModulusBitLen || seen[q] {
+			d.fail("bad RNS prime")
+		}
+		seen[q] = true
+		evk.moduli = append(evk.moduli, q)
+		evk.modulus.Mul(evk.modulus, new(big.Int).SetUint64(q))
+	}
+	if d.err != nil {
+		return nil
+	}
+	// The keys b'_i are read before the a'_i are expanded, so that the data
+	// bounds the size of both.
+	evk.digitB = d.residues(n, count-1, evk.moduli)
+	if d.flag() {
+		evk.seed = append([]byte(nil), d.next(negacyclic.SeedSize)...)
+		if d.err == nil {
+			evk.digitA = negacyclic.UniformRNSFromSeed(n, evk.moduli, count-1, evk.seed)
+		}
+	} else {
+		evk.digitA = d.residues(n, count-1, evk.moduli)
+	}
+	if d.err != nil {
+		return nil
+	}
+	return evk
+}
+
+// keyBoundFits reports whether keys modulo q_L can be in evaluation form with
+// respect to the given bound. The bound of an instance is q_L * P, and P has at
+// most Depth bits more than q_L when derived by NewInstance, since each prime
+// of the chain has at least BitLenP - 1 bits. Explicit moduli are held to the
+// same rule (see Moduli.check), so that their keys can be decoded.
+func keyBoundFits(qL, bound *big.Int) bool {
+	return qL.Cmp(bound) <= 0 && bound.BitLen() <= 2*qL.BitLen()+maxDepth
+}
+
+// zMultiplierCache shares the ZMultipliers rebuilt when decoding the keys of a
+// composite object, which all have the bound of their instance. When keys are
+// decoded for an instance (see Instance.DecodeArmored), the cache only holds
+// the ZMultiplier of the instance, so that the dimension and the bound read
+// from the data are checked before any multiplier is built.
+type zMultiplierCache struct {
+	zms      map[string]*negacyclic.ZMultiplier
+	instance *negacyclic.ZMultiplier
+}
+
+func newZMultiplierCache() zMultiplierCache {
+	return zMultiplierCache{zms: make(map[string]*negacyclic.ZMultiplier)}
+}
+
+// get returns the ZMultiplier of the given dimension and bound. It returns
+// ErrFingerprintMismatch if they are not the ones of the instance of the
+// cache, if any.
+func (cache zMultiplierCache) get(n int, bound *big.Int) (*negacyclic.ZMultiplier, error) {
+	if zm := cache.instance; zm != nil {
+		if n != zm.N || bound.Cmp(zm.Bound) != 0 {
+			return nil, ErrFingerprintMismatch
+		}
+		return zm, nil
+	}
+	id := strconv.Itoa(n) + ":" + bound.String()
+	if zm, ok := cache.zms[id]; ok {
+		return zm, nil
+	}
+	zm := negacyclic.NewBoundedZMultiplier(n, bound)
+	cache.zms[id] = zm
+	return zm, nil
+}
+
+// reduce returns the coefficients of x modulo q, in [0, q).
+func reduce(x *negacyclic.Polynomial, q *big.Int) []*big.Int {
+	res := make([]*big.Int, x.Deg())
+	for i, coeff := range x.Coeffs {
+		res[i] = new(big.Int).Mod(coeff, q)
+	}
+	return res
+}
+
+// residues returns the residues of the given coefficients modulo each prime.
+func residues(coeffs []*big.Int, moduli []uint64) *negacyclic.RNSPolynomial {
+	limbs := make([][]uint64, len(moduli))
+	aux := new(big.Int)
+	for i, q := range moduli {
+		bigQ := new(big.Int).SetUint64(q)
+		limbs[i] = make([]uint64, len(coeffs))
+		for j, coeff := range coeffs {
+			limbs[i][j] = aux.Mod(coeff, bigQ).Uint64()
+		}
+	}
+	return &negacyclic.RNSPolynomial{Coeffs: limbs}
+}
+
+func bitLen64(x uint64) int {
+	return new(big.Int).SetUint64(x).BitLen()
+}
+
+//
+// Encoding and decoding primitives
+//
+
+type encoder struct {
+	buf []byte
+}
+
+func newEncoder(kind byte) *encoder {
+	e := &encoder{buf: append([]byte(nil), serializationMagic...)}
+	e.buf = append(e.buf, SerializationVersion, kind)
+	return e
+}
+
+func (e *encoder) flag(b bool) {
+	if b {
+		e.buf = append(e.buf, 1)
+	} else {
+		e.buf = append(e.buf, 0)
+	}
+}
+
+func (e *encoder) uint32(x int) {
+	var tmp [4]byte
+	binary.BigEndian.PutUint32(tmp[:], uint32(x))
+	e.buf = append(e.buf, tmp[:]...)
+}
+
+func (e *encoder) uint64(x uint64) {
+	var tmp [8]byte
+	binary.BigEndian.PutUint64(tmp[:], x)
+	e.buf = append(e.buf, tmp[:]...)
+}
+
+// bytes writes the length of b, then b.
+func (e *encoder) bytes(b []byte) {
+	e.uint32(len(b))
+	e.buf = append(e.buf, b...)
+}
+
+// bigInt writes the absolute value of x.
+func (e *encoder) bigInt(x *big.Int) {
+	e.bytes(x.Bytes())
+}
+
+func (e *encoder) scale(x *big.Float) {
+	data, err := x.GobEncode()
+	if err != nil {
+		panic(err)
+	}
+	e.bytes(data)
+}
+
+func (e *encoder) parameters(pars *Parameters) {
+	for _, x := range []int{pars.N, pars.Depth, pars.BitLenP, pars.BitLenQ, pars.Hamming} {
+		e.uint32(x)
+	}
+	e.uint64(math.Float64bits(pars.Sigma))
+	e.flag(pars.RNS)
+}
+
+// packed writes the values, which must lie in [0, 2^width), on width bits
+// each. The last byte is padded with zeros.
+func (e *encoder) packed(values []*big.Int, width int) {
+	w := bitWriter{out: e.buf}
+	buf := make([]byte, (width+7)/8)
+	for _, value := range values {
+		raw := value.Bytes()
+		for i := range buf {
+			buf[i] = 0
+		}
+		copy(buf[len(buf)-len(raw):], raw)
+		w.writeBytes(buf, width)
+	}
+	e.buf = w.flush()
+}
+
+// packedWords is the word-sized counterpart of packed.
+func (e *encoder) packedWords(values []uint64, width int) {
+	w := bitWriter{out: e.buf}
+	var buf [8]byte
+	for _, value := range values {
+		binary.BigEndian.PutUint64(buf[:], value)
+		w.writeBytes(buf[8-(width+7)/8:], width)
+	}
+	e.buf = w.flush()
+}
+
+// residues writes the polynomials in evaluation form, limb by limb, on the bit
+// length of each prime.
+func (e *encoder) residues(polys []*negacyclic.RNSPolynomial, moduli []uint64) {
+	for _, x := range polys {
+		for i, q := range moduli {
+			e.packedWords(x.Coeffs[i], bitLen64(q))
+		}
+	}
+}
+
+// decoder reads serialized data. After the first failure, reads return zero
+// values, and the failure is reported by finish.
+type decoder struct {
+	data []byte
+	err  error
+}
+
+// newDecoder checks the header of data, for the given kind of object.
+func newDecoder(data []byte, kind byte) *decoder {
+	d := &decoder{data: data}
+	if !bytes.Equal(d.next(len(serializationMagic)), serializationMagic) {
+		d.fail("bad magic bytes")
+	}
+	if version := d.byte(); d.err == nil && version != SerializationVersion {
+		d.err = ErrUnsupportedVersion
+	}
+	if k := d.byte(); d.err == nil && k != kind {
+		d.fail("unexpected kind of object")
+	}
+	return d
+}
+
+func (d *decoder) fail(msg string) {
+	if d.err == nil {
+		d.err = fmt.Errorf("%w: %s", ErrMalformedData, msg)
+	}
+}
+
+// finish returns the first failure, if any, or a failure if data remains.
+func (d *decoder) finish() error {
+	if d.err == nil && len(d.data) != 0 {
+		d.fail("trailing data")
+	}
+	return d.err
+}
+
+func (d *decoder) next(n int) []byte {
+	if d.err != nil {
+		return nil
+	}
+	if n > len(d.data) {
+		d.fail("truncated data")
+		return nil
+	}
+	res := d.data[:n]
+	d.data = d.data[n:]
+	return res
+}
+
+func (d *decoder) byte() byte {
+	b := d.next(1)
+	if b == nil {
+		return 0
+	}
+	return b[0]
+}
+
+func (d *decoder) flag() bool {
+	b := d.byte()
+	if b > 1 {
+		d.fail("bad flag")
+	}
+	return b == 1
+}
+
+func (d *decoder) uint32() int {
+	b := d.next(4)
+	if b == nil {
+		return 0
+	}
+	return int(binary.BigEndian.Uint32(b))
+}
+
+func (d *decoder) uint64() uint64 {
+	b := d.next(8)
+	if b == nil {
+		return 0
+	}
+	return binary.BigEndian.Uint64(b)
+}
+
+func (d *decoder) bytes() []byte {
+	return d.next(d.uint32())
+}
+
+func (d *decoder) bigInt() *big.Int {
+	return new(big.Int).SetBytes(d.bytes())
+}
+
+// dimension reads a power of two in [2, maxDimension].
+func (d *decoder) dimension() int {
+	n := d.uint32()
+	if d.err == nil && (n < 2 || n > maxDimension || n&(n-1) != 0) {
+		d.fail("bad dimension")
+	}
+	return n
+}
+
+// parameters reads parameters of power of two dimension, and finite
+// non-negative standard deviation.
+func (d *decoder) parameters() *Parameters {
+	pars := &Parameters{N: d.dimension()}
+	for _, x := range []*int{&pars.Depth, &pars.BitLenP, &pars.BitLenQ, &pars.Hamming} {
+		*x = d.uint32()
+	}
+	pars.Sigma = math.Float64frombits(d.uint64())
+	if d.err == nil && !(pars.Sigma >= 0 && !math.IsInf(pars.Sigma, 1)) {
+		d.fail("bad standard deviation")
+	}
+	pars.RNS = d.flag()
+	return pars
+}
+
+// modulus reads an integer larger than 1.
+func (d *decoder) modulus() *big.Int {
+	q := d.bigInt()
+	if d.err == nil && q.Cmp(big.NewInt(1)) <= 0 {
+		d.fail("bad modulus")
+	}
+	return q
+}
+
+// scale reads a positive finite scale.
+func (d *decoder) scale() *big.Float {
+	data := d.bytes()
+	if d.err != nil {
+		return nil
+	}
+	scale := new(big.Float)
+	if err := scale.GobDecode(data); err != nil || scale.Sign() <= 0 || scale.IsInf() {
+		d.fail("bad scale")
+		return nil
+	}
+	return scale.SetPrec(ScalePrecision)
+}
+
+// bound reads a non-negative finite bound, written by encoder.scale.
+func (d *decoder) bound() *big.Float {
+	data := d.bytes()
+	if d.err != nil {
+		return nil
+	}
+	bound := new(big.Float)
+	if err := bound.GobDecode(data); err != nil || bound.Sign() < 0 || bound.IsInf() {
+		d.fail("bad bound")
+		return nil
+	}
+	return bound.SetPrec(ScalePrecision)
+}
+
+// packed reads count values written by encoder.packed, and checks that they
+// are smaller than the modulus, unless it is nil.
+func (d *decoder) packed(count, width int, modulus *big.Int) []*big.Int {
+	data := d.next((count*width + 7) / 8)
+	if d.err != nil {
+		return nil
+	}
+	r := bitReader{data: data}
+	buf := make([]byte, (width+7)/8)
+	values := make([]*big.Int, count)
+	for i := range values {
+		r.readBytes(buf, width)
+		values[i] = new(big.Int).SetBytes(buf)
+		if modulus != nil && values[i].Cmp(modulus) >= 0 {
+			d.fail("coefficient exceeds its modulus")
+		}
+	}
+	if r.acc != 0 {
+		d.fail("non-zero padding")
+	}
+	return values
+}
+
+// packedWords reads count values written by encoder.packedWords, and checks
+// that they are smaller than the modulus.
+func (d *decoder) packedWords(count, width int, modulus uint64) []uint64 {
+	data := d.next((count*width + 7) / 8)
+	if d.err != nil {
+		return nil
+	}
+	r := bitReader{data: data}
+	var buf [8]byte
+	values := make([]uint64, count)
+	for i := range values {
+		r.readBytes(buf[8-(width+7)/8:], width)
+		values[i] = binary.BigEndian.Uint64(buf[:])
+		if values[i] >= modulus {
+			d.fail("coefficient exceeds its modulus")
+		}
+	}
+	if r.acc != 0 {
+		d.fail("non-zero padding")
+	}
+	return values
+}
+
+// residues reads count polynomials of dimension n written by
+// encoder.residues, in evaluation form.
+func (d *decoder) residues(n, count int, moduli []uint64) []*negacyclic.RNSPolynomial {
+	polys := make([]*negacyclic.RNSPolynomial, count)
+	for k := range polys {
+		limbs := make([][]uint64, len(moduli))
+		for i, q := range moduli {
+			limbs[i] = d.packedWords(n, bitLen64(q), q)
+		}
+		polys[k] = negacyclic.NewRNSPolynomial(limbs, true)
+	}
+	return polys
+}
+
+// bitWriter appends bits to out, most significant first.
+type bitWriter struct {
+	out    []byte
+	acc, n uint // the n pending bits
+}
+
+// writeBytes writes the width low bits of the big-endian integer buf, of
+// (width+7)/8 bytes.
+func (w *bitWriter) writeBytes(buf []byte, width int) {
+	k := uint(width - 8*(len(buf)-1))
+	for _, b := range buf {
+		w.acc = w.acc<<k | uint(b)
+		w.n += k
+		if w.n >= 8 {
+			w.n -= 8
+			w.out = append(w.out, byte(w.acc>>w.n))
+			w.acc &= 1<<w.n - 1
+		}
+		k = 8
+	}
+}
+
+func (w *bitWriter) flush() []byte {
+	if w.n > 0 {
+		w.out = append(w.out, byte(w.acc<<(8-w.n)))
+	}
+	return w.out
+}
+
+// bitReader reads the bits written by a bitWriter.
+type bitReader struct {
+	data   []byte
+	acc, n uint // the n pending bits
+}
+
+// readBytes reads width bits into the big-endian integer buf, of (width+7)/8
+// bytes.
+func (r *bitReader) readBytes(buf []byte, width int) {
+	k := uint(width - 8*(len(buf)-1))
+	for i := range buf {
+		if r.n < k {
+			r.acc = r.acc<<8 | uint(r.data[0])
+			r.data = r.data[1:]
+			r.n += 8
+		}
+		r.n -= k
+		buf[i] = byte(r.acc >> r.n)
+		r.acc &= 1<<r.n - 1
+		k = 8
+	}
+}
@@ -0,0 +1,338 @@
+package ckks_test
+
+import (
+	"bytes"
+	"errors"
+	"reflect"
+	"strconv"
+	"testing"
+
+	"ckks"
+	"ckks/negacyclic"
+)
+
+func testSerialization(ins *ckks.Instance, t *testing.T) {
+	t.Run("plaintext_roundtrip", func(t *testing.T) { testPlaintextSerialization(ins, t) })
+	t.Run("ciphertext_roundtrip", func(t *testing.T) { testCiphertextSerialization(ins, t) })
+	t.Run("seeded_ciphertext", func(t *testing.T) { testSeededCiphertextSerialization(ins, t) })
+	t.Run("key_roundtrip", func(t *testing.T) { testKeySerialization(ins, t) })
+	t.Run("rotation_keys_roundtrip", func(t *testing.T) { testRotationKeysSerialization(ins, t) })
+	t.Run("malformed", func(t *testing.T) { testMalformedData(ins, t) })
+	t.Run("foreign_objects", func(t *testing.T) { testForeignObjects(ins, t) })
+	t.Run("armored", func(t *testing.T) { testArmor(ins, t) })
+}
+
+func testPlaintextSerialization(ins *ckks.Instance, t *testing.T) {
+	plt := precompEncDec.pltxs[0]
+	decoded := new(ckks.Plaintext)
+	roundtrip(t, plt, decoded)
+	checkSamePolynomial(t, decoded.GetPolynomial(), plt.GetPolynomial())
+	if decoded.Scale().Cmp(plt.Scale()) != 0 {
+		t.Fatalf("got scale %s, want %s", decoded.Scale(), plt.Scale())
+	}
+}
+
+func testCiphertextSerialization(ins *ckks.Instance, t *testing.T) {
+	key := precompEncDec.key
+	prod, err := ins.Mul(key.Evaluation, precompEncDec.ciphs[0], precompEncDec.ciphs[0])
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, ciph := range []*ckks.Ciphertext{precompEncDec.ciphs[0], ins.MulNoRelin(prod, prod)} {
+		decoded := new(ckks.Ciphertext)
+		roundtrip(t, ciph, decoded)
+		if decoded.Level() != ciph.Level() || decoded.Degree() != ciph.Degree() ||
+			decoded.Modulus().Cmp(ciph.Modulus()) != 0 || decoded.Scale().Cmp(ciph.Scale()) != 0 {
+			t.Fatal("level, degree, modulus and scale should be kept")
+		}
+		if decoded.MessageBound().Cmp(ciph.MessageBound()) != 0 || decoded.NoiseBound().Cmp(ciph.NoiseBound()) != 0 {
+			t.Fatal("the bounds should be kept")
+		}
+		checkSamePolynomial(t, ins.Decrypt(key.Secret, decoded).GetPolynomial(),
+			ins.Decrypt(key.Secret, ciph).GetPolynomial())
+	}
+
+	ntt := precompEncDec.ciphs[0].Clone()
+	ins.ToNTT(ntt)
+	if _, err := ntt.MarshalBinary(); err != ckks.ErrEvaluationForm {
+		t.Fatalf("expected ErrEvaluationForm, got %v", err)
+	}
+}
+
+func testSeededCiphertextSerialization(ins *ckks.Instance, t *testing.T) {
+	key := precompEncDec.key
+	seeded := ins.EncryptSk(key.Secret, precompEncDec.pltxs[0])
+	unseeded, err := ins.Add(seeded, ins.Encrypt(key.Public, precompEncDec.pltxs[0]))
+	if err != nil {
+		t.Fatal(err)
+	}
+	data := roundtrip(t, seeded, new(ckks.Ciphertext))
+	full := roundtrip(t, unseeded, new(ckks.Ciphertext))
+	if 2*len(data) > len(full)+256 {
+		t.Fatalf("seeded ciphertext takes %d bytes, unseeded %d", len(data), len(full))
+	}
+
+	decoded := new(ckks.Ciphertext)
+	roundtrip(t, seeded, decoded)
+	if !bytes.Equal(decoded.Seed(), seeded.Seed()) {
+		t.Fatal("the seed should be kept")
+	}
+	checkSamePolynomial(t, ins.Decrypt(key.Secret, decoded).GetPolynomial(),
+		ins.Decrypt(key.Secret, seeded).GetPolynomial())
+}
+
+func testKeySerialization(ins *ckks.Instance, t *testing.T) {
+	key := precompEncDec.key
+	decoded := new(ckks.Key)
+	roundtrip(t, key, decoded)
+	if !bytes.Equal(decoded.Public.Seed(), key.Public.Seed()) ||
+		!bytes.Equal(decoded.Evaluation.Seed(), key.Evaluation.Seed()) {
+		t.Fatal("the seeds should be kept")
+	}
+
+	// Decoded keys encrypt, decrypt and relinearize as the original ones.
+	ciph := ins.Encrypt(decoded.Public, precompEncDec.pltxs[0])
+	checkResult(ins.Decode(ins.Decrypt(decoded.Secret, ciph)), precompEncDec.msgs[0], t)
+
+	// Only b is shipped, a being replaced by its seed.
+	bitLen := ins.FirstModulus().BitLen()
+	pk, err := key.Public.MarshalBinary()
+	if err != nil {
+		t.Fatal(err)
+	}
+	evk, err := key.Evaluation.MarshalBinary()
+	if err != nil {
+		t.Fatal(err)
+	}
+	// In RNS mode, the evaluation key has a polynomial modulo q_L * P for each
+	// prime of the chain.
+	evkBits := 2*bitLen + 16
+	if ins.RNS {
+		evkBits = (ins.Depth + 1) * (bitLen + 64)
+	}
+	if len(pk) > ins.N*bitLen/8+256 || len(evk) > ins.N*evkBits/8+256 {
+		t.Fatalf("keys take %d and %d bytes", len(pk), len(evk))
+	}
+
+	prod, err := ins.Mul(key.Evaluation, ciph, ciph)
+	if err != nil {
+		t.Fatal(err)
+	}
+	prodDecoded, err := ins.Mul(decoded.Evaluation, ciph, ciph)
+	if err != nil {
+		t.Fatal(err)
+	}
+	checkSamePolynomial(t, ins.Decrypt(key.Secret, prodDecoded).GetPolynomial(),
+		ins.Decrypt(key.Secret, prod).GetPolynomial())
+
+	// Missing keys are kept missing.
+	public := &ckks.Key{Public: key.Public, Evaluation: key.Evaluation}
+	decoded = new(ckks.Key)
+	roundtrip(t, public, decoded)
+	if decoded.Secret != nil || decoded.Conjugation != nil || decoded.Public == nil {
+		t.Fatal("only the public parts of the key should be decoded")
+	}
+}
+
+func testRotationKeysSerialization(ins *ckks.Instance, t *testing.T) {
+	key := precompEncDec.key
+	rtk := ins.GenerateRotationKeys(key.Secret, []int{1, -1})
+	decoded := new(ckks.RotationKeys)
+	roundtrip(t, rtk, decoded)
+	for _, k := range []int{1, -1} {
+		want, err := ins.Rotate(rtk, precompEncDec.ciphs[0], k)
+		if err != nil {
+			t.Fatal(err)
+		}
+		got, err := ins.Rotate(decoded, precompEncDec.ciphs[0], k)
+		if err != nil {
+			t.Fatal(err)
+		}
+		checkSamePolynomial(t, ins.Decrypt(key.Secret, got).GetPolynomial(),
+			ins.Decrypt(key.Secret, want).GetPolynomial())
+	}
+}
+
+func testForeignObjects(ins *ckks.Instance, t *testing.T) {
+	if err := ins.CheckCiphertext(precompEncDec.ciphs[0]); err != nil {
+		t.Fatal(err)
+	}
+
+	// A ciphertext of a deeper instance decodes, but does not belong to ins
+	params := ins.Parameters
+	params.Depth++
+	deeper, err := ckks.NewInstance(&params)
+	if err != nil && err != ckks.ErrWarningInsecure {
+		t.Fatal(err)
+	}
+	key := deeper.GenerateKey()
+	plt, err := deeper.Encode(make([]complex128, deeper.N/2), deeper.GetP())
+	if err != nil {
+		t.Fatal(err)
+	}
+	data, err := deeper.EncryptSk(key.Secret, plt).MarshalBinary()
+	if err != nil {
+		t.Fatal(err)
+	}
+	decoded := new(ckks.Ciphertext)
+	if err := decoded.UnmarshalBinary(data); err != nil {
+		t.Fatal(err)
+	}
+	if err := ins.CheckCiphertext(decoded); err != ckks.ErrFingerprintMismatch {
+		t.Fatalf("expected ErrFingerprintMismatch, got %v", err)
+	}
+	if err := ins.CheckCiphertext(new(ckks.Ciphertext)); err != ckks.ErrFingerprintMismatch {
+		t.Fatalf("expected ErrFingerprintMismatch on an empty ciphertext, got %v", err)
+	}
+
+	// Keys of the deeper instance are rejected from their bound, even under
+	// the fingerprint of ins
+	text, err := deeper.EncodeArmored(key.Public)
+	if err != nil {
+		t.Fatal(err)
+	}
+	text = bytes.Replace(text, []byte(deeper.Fingerprint()), []byte(ins.Fingerprint()), 1)
+	if err := ins.DecodeArmored(text, new(ckks.PublicKey)); err != ckks.ErrFingerprintMismatch {
+		t.Fatalf("expected ErrFingerprintMismatch, got %v", err)
+	}
+}
+
+func testMalformedData(ins *ckks.Instance, t *testing.T) {
+	data, err := precompEncDec.ciphs[0].MarshalBinary()
+	if err != nil {
+		t.Fatal(err)
+	}
+	ciph := new(ckks.Ciphertext)
+	for name, bad := range map[string][]byte{
+		"empty":     nil,
+		"truncated": data[:len(data)-1],
+		"trailing":  append(append([]byte(nil), data...), 0),
+		"magic":     append([]byte("XKKS"), data[4:]...),
+		"degree":    withByte(data, 14, 0x7f),
+	} {
+		if err := ciph.UnmarshalBinary(bad); !errors.Is(err, ckks.ErrMalformedData) {
+			t.Fatalf("%s: expected ErrMalformedData, got %v", name, err)
+		}
+	}
+	if err := new(ckks.Plaintext).UnmarshalBinary(data); !errors.Is(err, ckks.ErrMalformedData) {
+		t.Fatalf("expected ErrMalformedData on a wrong kind, got %v", err)
+	}
+
+	version := append([]byte(nil), data...)
+	version[4]++
+	if err := ciph.UnmarshalBinary(version); err != ckks.ErrUnsupportedVersion {
+		t.Fatalf("expected ErrUnsupportedVersion, got %v", err)
+	}
+
+	// The first coefficient of the secret key is encoded as 2.
+	sk, err := precompEncDec.key.Secret.MarshalBinary()
+	if err != nil {
+		t.Fatal(err)
+	}
+	sk[10] = sk[10]&0x3f | 0x80
+	if err := new(ckks.SecretKey).UnmarshalBinary(sk); !errors.Is(err, ckks.ErrMalformedData) {
+		t.Fatalf("expected ErrMalformedData on a bad secret key, got %v", err)
+	}
+}
+
+// withByte returns a copy of data whose i-th byte is b.
+func withByte(data []byte, i int, b byte) []byte {
+	res := append([]byte(nil), data...)
+	res[i] = b
+	return res
+}
+
+func testArmor(ins *ckks.Instance, t *testing.T) {
+	key := precompEncDec.key
+	ciph := precompEncDec.ciphs[0]
+	text, err := ins.EncodeArmored(ciph)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.HasPrefix(text, []byte("-----BEGIN CKKS CIPHERTEXT-----\n")) ||
+		!bytes.Contains(text, []byte("Level: "+strconv.Itoa(ciph.Level())+"\n")) ||
+		!bytes.Contains(text, []byte("Fingerprint: "+ins.Fingerprint()+"\n")) {
+		t.Fatalf("unexpected armor:\n%s", text)
+	}
+	decoded := new(ckks.Ciphertext)
+	if err := ins.DecodeArmored(text, decoded); err != nil {
+		t.Fatal(err)
+	}
+	checkSamePolynomial(t, ins.Decrypt(key.Secret, decoded).GetPolynomial(),
+		ins.Decrypt(key.Secret, ciph).GetPolynomial())
+
+	for _, obj := range []interface{}{key, key.Public, key.Secret, key.Evaluation,
+		ins.GenerateRotationKeys(key.Secret, []int{1})} {
+		text, err := ins.EncodeArmored(obj)
+		if err != nil {
+			t.Fatal(err)
+		}
+		decoded := reflect.New(reflect.TypeOf(obj).Elem()).Interface()
+		if err := ins.DecodeArmored(text, decoded); err != nil {
+			t.Fatalf("%T: %v", obj, err)
+		}
+	}
+
+	for name, edit := range map[string][2]string{
+		"level":   {"Level: ", "Level: 1"},
+		"version": {"Version: " + strconv.Itoa(ckks.SerializationVersion), "Version: 0"},
+		"type":    {"CKKS CIPHERTEXT", "CKKS PUBLIC KEY"},
+	} {
+		bad := bytes.Replace(text, []byte(edit[0]), []byte(edit[1]), -1)
+		if err := ins.DecodeArmored(bad, new(ckks.Ciphertext)); !errors.Is(err, ckks.ErrMalformedData) {
+			t.Fatalf("%s: expected ErrMalformedData, got %v", name, err)
+		}
+	}
+	// A rejected block leaves the target untouched
+	target := ciph.Clone()
+	bad := bytes.Replace(text, []byte("Level: "), []byte("Level: 1"), 1)
+	if err := ins.DecodeArmored(bad, target); err == nil || target.Level() != ciph.Level() {
+		t.Fatalf("target modified by a rejected block: %v", err)
+	}
+	checkSamePolynomial(t, ins.Decrypt(key.Secret, target).GetPolynomial(),
+		ins.Decrypt(key.Secret, ciph).GetPolynomial())
+	if err := ins.DecodeArmored(text, new(ckks.PublicKey)); !errors.Is(err, ckks.ErrMalformedData) {
+		t.Fatalf("expected ErrMalformedData on a wrong type, got %v", err)
+	}
+	bad = bytes.Replace(text, []byte(ins.Fingerprint()), []byte("00"), 1)
+	if err := ins.DecodeArmored(bad, new(ckks.Ciphertext)); err != ckks.ErrFingerprintMismatch {
+		t.Fatalf("expected ErrFingerprintMismatch, got %v", err)
+	}
+	for _, other := range testInstances {
+		if other.ins != ins && other.ins.Fingerprint() == ins.Fingerprint() {
+			t.Fatalf("instance %s has the same fingerprint", other.name)
+		}
+	}
+}
+
+type serializable interface {
+	MarshalBinary() ([]byte, error)
+	UnmarshalBinary([]byte) error
+}
+
+// roundtrip encodes x, decodes it into y, and returns the encoding of x.
+func roundtrip(t *testing.T, x, y serializable) []byte {
+	data, err := x.MarshalBinary()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := y.UnmarshalBinary(data); err != nil {
+		t.Fatal(err)
+	}
+	again, err := y.MarshalBinary()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(again, data) {
+		t.Fatal("decoding then encoding should give the same data")
+	}
+	return data
+}
+
+func checkSamePolynomial(t *testing.T, got, want *negacyclic.Polynomial) {
+	for i := range want.Coeffs {
+		if got.Coeffs[i].Cmp(want.Coeffs[i]) != 0 {
+			t.Fatalf("got %s, want %s at index %d", got.Coeffs[i], want.Coeffs[i], i)
+		}
+	}
+}